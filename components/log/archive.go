@@ -0,0 +1,159 @@
+package log
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	@func: 切换后的日志文件异步归档到远端存储
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// Uploader 将本地文件上传到远端存储，key为远端对象名
+type Uploader interface {
+	Upload(ctx context.Context, key string, file *os.File) error
+}
+
+// ArchiveConfig 归档的配置，零值字段使用默认值
+type ArchiveConfig struct {
+	// Prefix 远端对象名的前缀，如logs/app-1
+	Prefix string
+	// QueueSize 等待上传的文件队列长度，队列满时放弃上传并计入失败，默认64
+	QueueSize int
+	// MaxRetries 每个文件最多重试次数，默认3
+	MaxRetries int
+	// RetryBackoff 首次重试前的等待时间，之后每次翻倍，默认1s
+	RetryBackoff time.Duration
+	// Timeout 单次上传的超时，默认5分钟
+	Timeout time.Duration
+}
+
+// Archiver 在日志文件切换后异步上传上一个文件，不阻塞日志写入
+type Archiver struct {
+	uploader Uploader
+	conf     ArchiveConfig
+	queue    chan string
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+	failures atomic.Uint64
+}
+
+// currentArchiver 通过EnableArchive注册的归档器
+var currentArchiver atomic.Pointer[Archiver]
+
+// NewArchiver 创建归档器并启动上传goroutine，需调用EnableArchive后才会接收切换的文件
+func NewArchiver(uploader Uploader, conf ArchiveConfig) *Archiver {
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 64
+	}
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = time.Second
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 5 * time.Minute
+	}
+	a := &Archiver{
+		uploader: uploader,
+		conf:     conf,
+		queue:    make(chan string, conf.QueueSize),
+		done:     make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+// EnableArchive 注册归档器，之后每次日志文件切换都会上传上一个文件，传nil取消
+func EnableArchive(a *Archiver) {
+	currentArchiver.Store(a)
+}
+
+// Failures 上传最终失败或因队列已满放弃的文件数
+func (a *Archiver) Failures() uint64 {
+	return a.failures.Load()
+}
+
+// Close 停止接收新文件，等待队列中的文件上传完成
+func (a *Archiver) Close() error {
+	a.once.Do(func() {
+		currentArchiver.CompareAndSwap(a, nil)
+		close(a.done)
+		a.wg.Wait()
+	})
+	return nil
+}
+
+// enqueue 加入上传队列，队列满时不等待
+func (a *Archiver) enqueue(filename string) {
+	select {
+	case <-a.done:
+		return
+	default:
+	}
+	select {
+	case a.queue <- filename:
+	default:
+		a.failures.Add(1)
+		errLogger.Warn("log: archive queue is full, skipping file", zap.String("file", filename))
+	}
+}
+
+func (a *Archiver) run() {
+	defer a.wg.Done()
+	for {
+		select {
+		case filename := <-a.queue:
+			a.upload(filename)
+		case <-a.done:
+			for {
+				select {
+				case filename := <-a.queue:
+					a.upload(filename)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// upload 按退避重试上传，全部失败后计入失败
+func (a *Archiver) upload(filename string) {
+	key := path.Join(a.conf.Prefix, filepath.Base(filename))
+	backoff := a.conf.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= a.conf.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = a.uploadOnce(key, filename); err == nil {
+			return
+		}
+	}
+	a.failures.Add(1)
+	errLogger.Error("log: failed to archive log file", zap.String("file", filename), zap.String("key", key), zap.Error(err))
+}
+
+func (a *Archiver) uploadOnce(key, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), a.conf.Timeout)
+	defer cancel()
+	return a.uploader.Upload(ctx, key, f)
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+/*
+	@func: 独立的审计日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+var auditLogger = zap.NewNop()
+
+// initAuditLogger 启用时创建只写审计文件的logger，无法打开文件时返回错误，不经过采样，直接同步写入文件
+func initAuditLogger(conf LoggerConfig, encoder zapcore.Encoder) error {
+	if !conf.Audit {
+		auditLogger = zap.NewNop()
+		return nil
+	}
+	writer, err := getLogWriter("-audit.log", conf.AuditRotate, conf.NoSymlink)
+	if err != nil {
+		return err
+	}
+	auditLogger = zap.New(zapcore.NewCore(encoder, writer, zapcore.InfoLevel), zap.AddCaller(), zap.WithClock(packageClock{}))
+	return nil
+}
+
+// AuditLogger 获取审计日志实例，未启用审计日志时返回不输出的logger
+func AuditLogger() *zap.Logger {
+	return auditLogger
+}
+
+// Audit 记录一条审计事件
+func Audit(event string, fields ...zap.Field) {
+	auditLogger.WithOptions(zap.AddCallerSkip(1)).Info(event, fields...)
+}
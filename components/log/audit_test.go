@@ -0,0 +1,41 @@
+package log
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAuditRetentionSurvivesMainCleanup(t *testing.T) {
+	chdirTemp(t)
+	mainOld := "log/zap-20200101-0000.log"
+	auditKept := "log/audit/zap-20200101-0000-audit.log"
+	writeAged(t, mainOld, 30*24*time.Hour)
+	writeAged(t, auditKept, 30*24*time.Hour)
+
+	if err := InitLoggerWithConfig(LoggerConfig{Env: "test", DisableStdout: true, NoSymlink: true, Audit: true}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+	logger.Info("trigger main rotation")
+	Audit("trigger audit rotation")
+
+	waitRemoved(t, mainOld)
+	if _, err := os.Stat(auditKept); err != nil {
+		t.Fatalf("audit log removed by main MaxAge: %v", err)
+	}
+}
+
+func TestAuditOpenFailureReturnsError(t *testing.T) {
+	chdirTemp(t)
+	// 同名的普通文件使审计日志目录无法创建
+	if err := os.MkdirAll("log", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("log/audit", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitLoggerWithConfig(LoggerConfig{DisableStdout: true, NoSymlink: true, Audit: true}); err == nil {
+		t.Fatal("InitLoggerWithConfig succeeded with an unwritable audit directory")
+	}
+}
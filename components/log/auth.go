@@ -0,0 +1,57 @@
+package log
+
+import "go.uber.org/zap"
+
+/*
+	@func: 认证事件日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const (
+	AuthEventLogin      = "login"
+	AuthEventToken      = "token_validation"
+	AuthEventPermission = "permission"
+)
+
+const (
+	AuthOutcomeSuccess = "success"
+	AuthOutcomeFailure = "failure"
+	AuthOutcomeDenied  = "denied"
+)
+
+// AuthEvent 一次认证或鉴权事件，不包含任何凭证字段，调用方也不应通过Fields传入密码或token
+type AuthEvent struct {
+	// Event 事件类型，如AuthEventLogin
+	Event string
+	// User 用户标识
+	User string
+	// Outcome 结果，如AuthOutcomeSuccess
+	Outcome string
+	// Reason 失败或拒绝的原因
+	Reason string
+	// IP 客户端IP
+	IP string
+	// Fields 额外字段
+	Fields []zap.Field
+}
+
+// AuthLog 统一记录认证事件，成功记为Info，失败和拒绝记为Warn，启用审计日志时同时写入审计日志
+func AuthLog(event AuthEvent) {
+	fields := append([]zap.Field{
+		zap.String("event", event.Event),
+		zap.String("user", event.User),
+		zap.String("outcome", event.Outcome),
+		zap.String("ip", event.IP),
+	}, event.Fields...)
+	if event.Reason != "" {
+		fields = append(fields, zap.String("reason", event.Reason))
+	}
+	l := logger.WithOptions(zap.AddCallerSkip(1))
+	if event.Outcome == AuthOutcomeSuccess {
+		l.Info("[auth]", fields...)
+	} else {
+		l.Warn("[auth]", fields...)
+	}
+	auditLogger.WithOptions(zap.AddCallerSkip(1)).Info("[auth]", fields...)
+}
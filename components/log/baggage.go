@@ -0,0 +1,49 @@
+package log
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+	@func: 解析W3C baggage请求头
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// pickBaggage 从baggage请求头中挑出白名单内的key，一个都没有时返回nil
+func pickBaggage(header http.Header, keys []string) headerValues {
+	if len(keys) == 0 {
+		return nil
+	}
+	var picked headerValues
+	for _, line := range header.Values("baggage") {
+		for _, member := range strings.Split(line, ",") {
+			// 成员格式为key=value;property，property忽略
+			member, _, _ = strings.Cut(member, ";")
+			key, value, ok := strings.Cut(member, "=")
+			if !ok {
+				continue
+			}
+			key = strings.TrimSpace(key)
+			if !containsString(keys, key) {
+				continue
+			}
+			if unescaped, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+				value = unescaped
+			}
+			picked = append(picked, headerValue{name: key, value: value})
+		}
+	}
+	return picked
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
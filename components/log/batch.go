@@ -0,0 +1,234 @@
+package log
+
+import (
+	"bytes"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	@func: 访问日志批量写入
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const (
+	// defaultBatchSize 只设置了BatchInterval时每批的条数
+	defaultBatchSize = 100
+	// defaultBatchInterval 只设置了BatchSize时的最长刷新间隔
+	defaultBatchInterval = time.Second
+)
+
+var (
+	// groupSinks 普通日志的各个输出端，批量刷新期间的写入会合并为一次
+	groupSinks atomic.Pointer[[]*groupWriteSyncer]
+
+	accessBatchesMu sync.Mutex
+	accessBatches   []*accessBatch
+)
+
+// groupWriteSyncer 在begin和end之间缓存写入的内容，end时一次写出
+type groupWriteSyncer struct {
+	mu    sync.Mutex
+	ws    zapcore.WriteSyncer
+	depth int
+	buf   bytes.Buffer
+}
+
+func newGroupWriteSyncer(ws zapcore.WriteSyncer) *groupWriteSyncer {
+	return &groupWriteSyncer{ws: ws}
+}
+
+func (w *groupWriteSyncer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.depth > 0 {
+		return w.buf.Write(p)
+	}
+	return w.ws.Write(p)
+}
+
+func (w *groupWriteSyncer) Sync() error {
+	return w.ws.Sync()
+}
+
+func (w *groupWriteSyncer) begin() {
+	w.mu.Lock()
+	w.depth++
+	w.mu.Unlock()
+}
+
+func (w *groupWriteSyncer) end() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.depth--
+	if w.depth == 0 && w.buf.Len() > 0 {
+		w.ws.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// accessBatch 缓存访问日志，达到size条或每隔interval一起写出
+type accessBatch struct {
+	mu      sync.Mutex
+	entries []batchedEntry
+	size    int
+	// closed 为true时不再缓存，写入的访问日志立即写出
+	closed bool
+	ticker *time.Ticker
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// batchedEntry 已通过各级core检查的访问日志，只等待写出
+type batchedEntry struct {
+	checked *zapcore.CheckedEntry
+	fields  []zapcore.Field
+}
+
+func newAccessBatch(size int, interval time.Duration) *accessBatch {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+	b := &accessBatch{size: size, ticker: time.NewTicker(interval), done: make(chan struct{})}
+	accessBatchesMu.Lock()
+	accessBatches = append(accessBatches, b)
+	accessBatchesMu.Unlock()
+	b.wg.Add(1)
+	go b.run()
+	return b
+}
+
+// run 定时写出，CloseAccessLogs后退出
+func (b *accessBatch) run() {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.ticker.C:
+			b.flush()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// close 停止定时写出并写出剩余的访问日志，可重复调用
+func (b *accessBatch) close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	b.mu.Unlock()
+	b.ticker.Stop()
+	close(b.done)
+	b.wg.Wait()
+	b.flush()
+}
+
+func (b *accessBatch) add(e batchedEntry) {
+	b.mu.Lock()
+	b.entries = append(b.entries, e)
+	full := len(b.entries) >= b.size || b.closed
+	b.mu.Unlock()
+	if full {
+		b.flush()
+	}
+}
+
+// flush 写出缓存的访问日志，各输出端只写入一次
+func (b *accessBatch) flush() {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+	if len(entries) == 0 {
+		return
+	}
+	var sinks []*groupWriteSyncer
+	if p := groupSinks.Load(); p != nil {
+		sinks = *p
+	}
+	for _, s := range sinks {
+		s.begin()
+	}
+	for _, e := range entries {
+		e.checked.Write(e.fields...)
+	}
+	for _, s := range sinks {
+		s.end()
+	}
+}
+
+// logger 把访问日志写入本批次的logger
+func (b *accessBatch) logger() *zap.Logger {
+	return WithoutCaller().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &batchCore{Core: core, batch: b}
+	}))
+}
+
+// batchCore 先经由被包装的core检查，通过采样、过滤、级别等检查的日志连同检查结果存入accessBatch，
+// 使采样等统计在请求结束时即生效，写出时不再重复检查
+type batchCore struct {
+	zapcore.Core
+	batch *accessBatch
+}
+
+func (c *batchCore) With(fields []zapcore.Field) zapcore.Core {
+	return &batchCore{Core: c.Core.With(fields), batch: c.batch}
+}
+
+func (c *batchCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	checked := c.Core.Check(ent, nil)
+	if checked == nil {
+		return ce
+	}
+	return ce.AddCore(ent, batchedCore{Core: c.Core, batch: c.batch, checked: checked})
+}
+
+func (c *batchCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeChecked(c.Core, ent, fields)
+}
+
+// batchedCore 一条已检查的访问日志，Write时存入accessBatch
+type batchedCore struct {
+	zapcore.Core
+	batch   *accessBatch
+	checked *zapcore.CheckedEntry
+}
+
+func (c batchedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// logger在Check之后才填入调用位置等信息
+	c.checked.Entry = ent
+	c.batch.add(batchedEntry{checked: c.checked, fields: fields})
+	return nil
+}
+
+// FlushAccessLogs 立即写出所有批量缓存的访问日志，开启批量写入时应在退出前调用
+func FlushAccessLogs() {
+	accessBatchesMu.Lock()
+	batches := append([]*accessBatch(nil), accessBatches...)
+	accessBatchesMu.Unlock()
+	for _, b := range batches {
+		b.flush()
+	}
+}
+
+// CloseAccessLogs 写出所有批量缓存的访问日志并停止各批次的定时写出goroutine，
+// 之后已创建的GinLogger仍可使用，访问日志改为立即写出，用于退出流程或测试中释放goroutine
+func CloseAccessLogs() {
+	accessBatchesMu.Lock()
+	batches := accessBatches
+	accessBatches = nil
+	accessBatchesMu.Unlock()
+	for _, b := range batches {
+		b.close()
+	}
+}
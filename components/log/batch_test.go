@@ -0,0 +1,102 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newBatchedEngine(t *testing.T, conf LoggerConfig) *gin.Engine {
+	t.Helper()
+	conf.DisableStdout = true
+	conf.RecentLogs = 100
+	conf.FileEncoding = EncodingJSON
+	if err := InitLoggerWithConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		CloseAccessLogs()
+		InitLoggerWithConfig(LoggerConfig{DisableStdout: true})
+	})
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinLoggerWithConfig(GinLoggerConfig{BatchSize: 100, BatchInterval: time.Hour}))
+	r.GET("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func pendingAccessLogs() int {
+	accessBatchesMu.Lock()
+	defer accessBatchesMu.Unlock()
+	var n int
+	for _, b := range accessBatches {
+		b.mu.Lock()
+		n += len(b.entries)
+		b.mu.Unlock()
+	}
+	return n
+}
+
+func countRecent(substr string) int {
+	var n int
+	for _, line := range RecentLogs() {
+		if strings.Contains(line, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBatchChecksBeforeBuffering(t *testing.T) {
+	r := newBatchedEngine(t, LoggerConfig{
+		SuppressMessages: []string{"/health"},
+		Sampling:         &SamplingConfig{Initial: 1, Thereafter: 1000},
+	})
+	dropped := Stats().DroppedBySampling
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	for i := 0; i < 3; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	}
+	// 被过滤和被采样丢弃的日志不进入批次，采样计数在请求结束时即更新
+	if n := pendingAccessLogs(); n != 1 {
+		t.Fatalf("pending access logs = %d, want 1", n)
+	}
+	if got := Stats().DroppedBySampling - dropped; got != 2 {
+		t.Fatalf("dropped by sampling = %d, want 2", got)
+	}
+	FlushAccessLogs()
+	if n := countRecent(`"msg":"/users"`); n != 1 {
+		t.Fatalf("flushed /users logs = %d, want 1", n)
+	}
+	if n := countRecent(`"msg":"/health"`); n != 0 {
+		t.Fatalf("suppressed /health was written %d times", n)
+	}
+}
+
+func TestCloseAccessLogsStopsBatching(t *testing.T) {
+	r := newBatchedEngine(t, LoggerConfig{})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/before", nil))
+	accessBatchesMu.Lock()
+	batches := append([]*accessBatch(nil), accessBatches...)
+	accessBatchesMu.Unlock()
+
+	CloseAccessLogs()
+	if n := countRecent(`"msg":"/before"`); n != 1 {
+		t.Fatalf("CloseAccessLogs did not flush pending logs, got %d", n)
+	}
+	for _, b := range batches {
+		select {
+		case <-b.done:
+		default:
+			t.Fatal("batch goroutine was not stopped")
+		}
+	}
+	// 关闭后的GinLogger立即写出
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/after", nil))
+	if n := countRecent(`"msg":"/after"`); n != 1 {
+		t.Fatalf("access log after close was not written immediately, got %d", n)
+	}
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"sync"
+)
+
+/*
+	@func: 请求级Debug日志缓冲，请求失败时才输出
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const bufferedLoggerKey = "go_components_record/log.buffered"
+
+// defaultBufferEntries 每个请求默认最多缓冲的条数
+const defaultBufferEntries = 256
+
+// bufferedEntry 缓冲的一条日志及其要写入的core
+type bufferedEntry struct {
+	core   zapcore.Core
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+// entryBuffer 一个请求内所有子logger共用的缓冲区
+type entryBuffer struct {
+	mu      sync.Mutex
+	entries []bufferedEntry
+	max     int
+}
+
+func (b *entryBuffer) add(e bufferedEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) >= b.max {
+		stats.droppedByBufferOverflow.Add(1)
+		return
+	}
+	b.entries = append(b.entries, e)
+}
+
+// flush 写出缓冲的日志，failed为true时带上forceWriteField跳过级别检查，否则按原core的级别判断
+func (b *entryBuffer) flush(failed bool) {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+	for _, e := range entries {
+		if failed {
+			e.core.Write(e.entry, append(e.fields[:len(e.fields):len(e.fields)], forceWriteField))
+		} else if ce := e.core.Check(e.entry, nil); ce != nil {
+			ce.Write(e.fields...)
+		}
+	}
+}
+
+// bufferCore Debug级别的日志不论logger是否启用Debug都先写入缓冲区，其余级别直接写入
+type bufferCore struct {
+	zapcore.Core
+	buf *entryBuffer
+}
+
+func (c *bufferCore) Enabled(lvl zapcore.Level) bool {
+	return lvl == zapcore.DebugLevel || c.Core.Enabled(lvl)
+}
+
+func (c *bufferCore) With(fields []zapcore.Field) zapcore.Core {
+	return &bufferCore{Core: c.Core.With(fields), buf: c.buf}
+}
+
+func (c *bufferCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level == zapcore.DebugLevel {
+		return ce.AddCore(ent, c)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *bufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.buf.add(bufferedEntry{core: c.Core, entry: ent, fields: fields})
+	return nil
+}
+
+// GinDebugBuffer 为每个请求安装缓冲Debug日志的logger，响应状态>=500或panic时即使logger未启用Debug也输出缓冲的日志，
+// 否则只输出logger级别本来就会记录的部分
+// maxEntries为每个请求最多缓冲的条数，<=0时使用默认值，超出的部分计入DroppedByBufferOverflow
+func GinDebugBuffer(maxEntries int) gin.HandlerFunc {
+	if maxEntries <= 0 {
+		maxEntries = defaultBufferEntries
+	}
+	return func(c *gin.Context) {
+		buf := &entryBuffer{max: maxEntries}
+		c.Set(bufferedLoggerKey, logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &bufferCore{Core: core, buf: buf}
+		})))
+		defer func() {
+			if err := recover(); err != nil {
+				buf.flush(true)
+				panic(err)
+			}
+		}()
+		c.Next()
+
+		buf.flush(c.Writer.Status() >= http.StatusInternalServerError)
+	}
+}
+
+// BufferedLogger 获取GinDebugBuffer或GinFlightRecorder安装的请求级logger，未安装或c为nil时返回全局logger
+func BufferedLogger(c *gin.Context) *zap.Logger {
+	if c == nil {
+		return logger
+	}
+	if l, ok := c.Get(bufferedLoggerKey); ok {
+		return l.(*zap.Logger)
+	}
+	return logger
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestDebugBufferFlushesBelowLoggerLevelOnFailure(t *testing.T) {
+	cases := []struct {
+		level  zapcore.Level
+		status int
+		want   bool
+	}{
+		{zapcore.InfoLevel, http.StatusInternalServerError, true},
+		{zapcore.InfoLevel, http.StatusOK, false},
+		{zapcore.DebugLevel, http.StatusOK, true},
+		{zapcore.DebugLevel, http.StatusBadGateway, true},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tc := range cases {
+		logs, restore := ObserveForTest(tc.level)
+		r := gin.New()
+		r.Use(GinDebugBuffer(0))
+		r.GET("/", func(c *gin.Context) {
+			BufferedLogger(c).Debug("buffered debug")
+			BufferedLogger(c).Info("direct info")
+			status, _ := strconv.Atoi(c.Query("status"))
+			c.Status(status)
+		})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?status="+strconv.Itoa(tc.status), nil))
+		restore()
+
+		if _, got := logs.FindEntry("buffered debug"); got != tc.want {
+			t.Errorf("level %s status %d: debug written = %v, want %v", tc.level, tc.status, got, tc.want)
+		}
+		if _, ok := logs.FindEntry("direct info"); !ok {
+			t.Errorf("level %s status %d: info entry missing", tc.level, tc.status)
+		}
+	}
+}
+
+func TestDebugBufferFlushesOnPanic(t *testing.T) {
+	logs, restore := ObserveForTest(zapcore.InfoLevel)
+	defer restore()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ interface{}) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	r.Use(GinDebugBuffer(0))
+	r.GET("/", func(c *gin.Context) {
+		BufferedLogger(c).Debug("before panic")
+		panic("boom")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if _, ok := logs.FindEntry("before panic"); !ok {
+		t.Fatal("debug entry buffered before a panic was not written")
+	}
+}
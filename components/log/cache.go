@@ -0,0 +1,92 @@
+package log
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+	@func: 记录缓存的读写操作
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// CacheLogConfig 缓存日志的配置
+type CacheLogConfig struct {
+	// Name 缓存名，如redis、local，记录为cache字段
+	Name string
+	// HashKeys 为true时key以SHA-256摘要的前16位十六进制记录，用于key中含手机号、邮箱等隐私信息的缓存
+	HashKeys bool
+	// Salt HashKeys时使用的HMAC密钥，为空时使用不加盐的SHA-256，可枚举的key建议设置
+	Salt string
+}
+
+// CacheLog 以Debug记录缓存的get、set操作，日志通过ctx上的logger输出
+type CacheLog struct {
+	conf CacheLogConfig
+}
+
+// NewCacheLog 按配置创建缓存日志记录器
+func NewCacheLog(conf CacheLogConfig) *CacheLog {
+	return &CacheLog{conf: conf}
+}
+
+// CacheFields 缓存操作的统一字段，op为get、set、del等，key需要脱敏时传入CacheLog.Key的结果
+func CacheFields(op, key string, cost time.Duration) []zap.Field {
+	return []zap.Field{
+		zap.String("cache_op", op),
+		zap.String("cache_key", key),
+		zap.Duration("cost", cost),
+	}
+}
+
+// Key 按配置返回记录用的key，HashKeys时为摘要
+func (c *CacheLog) Key(key string) string {
+	if !c.conf.HashKeys {
+		return key
+	}
+	var sum []byte
+	if c.conf.Salt != "" {
+		mac := hmac.New(sha256.New, []byte(c.conf.Salt))
+		mac.Write([]byte(key))
+		sum = mac.Sum(nil)
+	} else {
+		s := sha256.Sum256([]byte(key))
+		sum = s[:]
+	}
+	return hex.EncodeToString(sum[:8])
+}
+
+// Get 记录一次读操作是否命中及耗时
+func (c *CacheLog) Get(ctx context.Context, key string, hit bool, cost time.Duration, fields ...zap.Field) {
+	c.log(ctx, "get", key, hit, cost, nil, fields)
+}
+
+// Set 记录一次写操作及耗时，err不为nil时一并记录
+func (c *CacheLog) Set(ctx context.Context, key string, cost time.Duration, err error, fields ...zap.Field) {
+	c.log(ctx, "set", key, false, cost, err, fields)
+}
+
+// log 未启用Debug时不计算key的摘要和字段
+func (c *CacheLog) log(ctx context.Context, op, key string, hit bool, cost time.Duration, err error, fields []zap.Field) {
+	l := LoggerFromContext(ctx).WithOptions(zap.AddCallerSkip(2))
+	ce := l.Check(zap.DebugLevel, "[cache] "+op)
+	if ce == nil {
+		return
+	}
+	all := CacheFields(op, c.Key(key), cost)
+	if op == "get" {
+		all = append(all, zap.Bool("hit", hit))
+	}
+	if c.conf.Name != "" {
+		all = append(all, zap.String("cache", c.conf.Name))
+	}
+	if err != nil {
+		all = append(all, zap.Error(err))
+	}
+	ce.Write(append(all, fields...)...)
+}
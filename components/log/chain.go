@@ -0,0 +1,94 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+	"time"
+)
+
+/*
+	@func: 中间件链的耗时统计
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const chainTimingsKey = "go_components_record/log.chain_timings"
+
+// chainTiming 一个被Instrument包装的handler的耗时，inclusive包含其后续handler的耗时
+type chainTiming struct {
+	name      string
+	inclusive time.Duration
+}
+
+type chainTimings struct {
+	mu    sync.Mutex
+	steps []chainTiming
+}
+
+// Instrument 包装中间件或handler以统计耗时，配合GinLoggerConfig.LogHandlerChain使用，
+// 按注册顺序包装每个中间件和最终的handler时，可得到每一步准确的自身耗时
+func Instrument(name string, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timings := getChainTimings(c)
+		timings.mu.Lock()
+		i := len(timings.steps)
+		timings.steps = append(timings.steps, chainTiming{name: name})
+		timings.mu.Unlock()
+		start := now()
+		h(c)
+		cost := now().Sub(start)
+		timings.mu.Lock()
+		timings.steps[i].inclusive = cost
+		timings.mu.Unlock()
+	}
+}
+
+func getChainTimings(c *gin.Context) *chainTimings {
+	if v, ok := c.Get(chainTimingsKey); ok {
+		return v.(*chainTimings)
+	}
+	timings := &chainTimings{}
+	c.Set(chainTimingsKey, timings)
+	return timings
+}
+
+// chainFields handler链的长度，以及Instrument统计到的每一步自身耗时和中间件总开销
+func chainFields(c *gin.Context, cost time.Duration) []zap.Field {
+	fields := []zap.Field{zap.Int("handlers", len(c.HandlerNames()))}
+	v, ok := c.Get(chainTimingsKey)
+	if !ok {
+		return fields
+	}
+	timings := v.(*chainTimings)
+	timings.mu.Lock()
+	steps := append([]chainTiming(nil), timings.steps...)
+	timings.mu.Unlock()
+	if len(steps) == 0 {
+		return fields
+	}
+	return append(fields,
+		zap.Array("timings", chainSteps(steps)),
+		zap.Duration("middleware_overhead", cost-steps[len(steps)-1].inclusive),
+	)
+}
+
+// chainSteps 输出每一步的自身耗时，即其inclusive减去下一步的inclusive
+type chainSteps []chainTiming
+
+func (s chainSteps) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for i, step := range s {
+		self := step.inclusive
+		if i+1 < len(s) {
+			self -= s[i+1].inclusive
+		}
+		name, self := step.name, self
+		enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", name)
+			enc.AddDuration("self", self)
+			return nil
+		}))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+	"time"
+)
+
+/*
+	@func: 请求内的耗时检查点
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+type checkpointsKey struct{}
+
+// checkpoint 一个检查点的名称和时间
+type checkpoint struct {
+	name string
+	at   time.Time
+}
+
+// requestCheckpoints 一个请求内按顺序记录的检查点
+type requestCheckpoints struct {
+	mu     sync.Mutex
+	start  time.Time
+	points []checkpoint
+}
+
+// installCheckpoints 在请求的context上安装检查点列表
+func installCheckpoints(c *gin.Context, start time.Time) *requestCheckpoints {
+	points := &requestCheckpoints{start: start}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), checkpointsKey{}, points))
+	return points
+}
+
+// Checkpoint 在ctx所属请求的时间线上记录名为name的检查点，如Checkpoint(ctx, "auth done")，
+// ctx需来自c.Request.Context()，GinLogger未开启LogCheckpoints时忽略
+func Checkpoint(ctx context.Context, name string) {
+	if ctx == nil {
+		return
+	}
+	points, _ := ctx.Value(checkpointsKey{}).(*requestCheckpoints)
+	if points == nil {
+		return
+	}
+	at := now()
+	points.mu.Lock()
+	points.points = append(points.points, checkpoint{name: name, at: at})
+	points.mu.Unlock()
+}
+
+// field 以timeline数组输出各检查点，at为距请求开始的时间，delta为距上一个检查点的时间，没有检查点时不输出
+func (r *requestCheckpoints) field() zap.Field {
+	r.mu.Lock()
+	points := append([]checkpoint(nil), r.points...)
+	r.mu.Unlock()
+	if len(points) == 0 {
+		return zap.Skip()
+	}
+	return zap.Array("timeline", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		prev := r.start
+		for _, p := range points {
+			p, delta := p, p.at.Sub(prev)
+			prev = p.at
+			enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("name", p.name)
+				enc.AddDuration("at", p.at.Sub(r.start))
+				enc.AddDuration("delta", delta)
+				return nil
+			}))
+		}
+		return nil
+	}))
+}
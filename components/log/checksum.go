@@ -0,0 +1,132 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+/*
+	@func: 切换后的日志文件写入校验和，用于发现篡改
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// ChecksumKeyEnv 设置后校验文件使用以其值为密钥的HMAC-SHA256，否则使用SHA-256
+const ChecksumKeyEnv = "LOG_CHECKSUM_KEY"
+
+// 校验文件的扩展名，内容为"十六进制摘要  文件名"，.sha256与sha256sum的格式相同
+const (
+	checksumExt = ".sha256"
+	hmacExt     = ".hmac"
+)
+
+// checksumKey 从环境变量读取HMAC密钥，未设置时返回nil
+func checksumKey() []byte {
+	if key := os.Getenv(ChecksumKeyEnv); key != "" {
+		return []byte(key)
+	}
+	return nil
+}
+
+// fileDigest 计算文件的摘要，key非空时为HMAC-SHA256
+func fileDigest(path string, key []byte) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var h hash.Hash
+	if key != nil {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeChecksum 为切换后不再写入的日志文件写入校验文件，返回校验文件的路径
+func writeChecksum(path string) (string, error) {
+	key := checksumKey()
+	digest, err := fileDigest(path, key)
+	if err != nil {
+		return "", err
+	}
+	sumPath := path + checksumExt
+	if key != nil {
+		sumPath = path + hmacExt
+	}
+	// 再次为同一文件写入校验文件时需要覆盖，不能使用只读权限
+	w, err := fsys.OpenFile(sumPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintf(w, "%s  %s\n", digest, filepath.Base(path)); err != nil {
+		w.Close()
+		return "", err
+	}
+	return sumPath, w.Close()
+}
+
+// readFile 通过fsys读取整个文件
+func readFile(name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// VerifyLogFile 校验日志文件与切换时写入的校验文件是否一致，文件被修改或没有校验文件时返回错误，
+// 使用HMAC的校验文件需要设置与写入时相同的LOG_CHECKSUM_KEY，设置了LOG_CHECKSUM_KEY时只接受.hmac，
+// 避免删除.hmac后以不带密钥的.sha256冒充
+func VerifyLogFile(path string) error {
+	key := checksumKey()
+	data, err := readFile(path + hmacExt)
+	switch {
+	case err == nil:
+		if key == nil {
+			return fmt.Errorf("log: %s is signed with hmac, %s is required", path, ChecksumKeyEnv)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	case key != nil:
+		return fmt.Errorf("log: no hmac for %s while %s is set: %w", path, ChecksumKeyEnv, err)
+	default:
+		if data, err = readFile(path + checksumExt); err != nil {
+			return fmt.Errorf("log: no checksum for %s: %w", path, err)
+		}
+	}
+	want, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	got, err := fileDigest(path, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(got), []byte(want)) {
+		return fmt.Errorf("log: checksum mismatch for %s, the file may have been modified", path)
+	}
+	return nil
+}
+
+// pruneChecksums 删除日志文件已被过期清理的校验文件
+func pruneChecksums(suffix string) {
+	pattern := regexp.MustCompile(`^zap-\d{8}-\d{4}` + regexp.QuoteMeta(suffix) + `(\.\d+)?(\.sha256|\.hmac)$`)
+	for _, b := range findBackups(streamDir(suffix), pattern, dateDirDepth) {
+		logFile := strings.TrimSuffix(strings.TrimSuffix(b.path, checksumExt), hmacExt)
+		if _, err := fsys.Stat(logFile); errors.Is(err, os.ErrNotExist) {
+			fsys.Remove(b.path)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestChecksumOnMemFS(t *testing.T) {
+	m := NewMemFS()
+	defer SetFileSystemForTest(m)()
+	t.Setenv(ChecksumKeyEnv, "")
+	m.MkdirAll("log/error", 0755)
+	file := "log/error/zap-20231009-1000-error.log"
+	createMemFile(t, m, file)
+
+	// 第二次写入需要覆盖第一次的校验文件
+	for i := 0; i < 2; i++ {
+		sumPath, err := writeChecksum(file)
+		if err != nil {
+			t.Fatalf("write checksum #%d: %v", i+1, err)
+		}
+		if sumPath != file+checksumExt {
+			t.Fatalf("sumPath = %s", sumPath)
+		}
+	}
+	data, _ := m.ReadFile(file + checksumExt)
+	if !strings.HasSuffix(string(data), "  zap-20231009-1000-error.log\n") || strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("checksum file = %q", data)
+	}
+	if err := VerifyLogFile(file); err != nil {
+		t.Fatalf("verify untouched file: %v", err)
+	}
+
+	w, _ := m.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	io.WriteString(w, "tampered")
+	w.Close()
+	if err := VerifyLogFile(file); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Fatalf("verify tampered file: %v", err)
+	}
+
+	m.Remove(file)
+	pruneChecksums("-error.log")
+	if _, err := m.Stat(file + checksumExt); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("checksum of removed file not pruned: %v", err)
+	}
+}
+
+func TestVerifyLogFileHMACRequiresKey(t *testing.T) {
+	m := NewMemFS()
+	defer SetFileSystemForTest(m)()
+	m.MkdirAll("log", 0755)
+	file := "log/zap-20231009-1000.log"
+	createMemFile(t, m, file)
+
+	t.Setenv(ChecksumKeyEnv, "secret")
+	if _, err := writeChecksum(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyLogFile(file); err != nil {
+		t.Fatalf("verify with key: %v", err)
+	}
+	t.Setenv(ChecksumKeyEnv, "")
+	if err := VerifyLogFile(file); err == nil || !strings.Contains(err.Error(), ChecksumKeyEnv) {
+		t.Fatalf("verify without key: %v", err)
+	}
+}
+
+func TestVerifyLogFileRejectsSHA256WhenKeySet(t *testing.T) {
+	m := NewMemFS()
+	defer SetFileSystemForTest(m)()
+	m.MkdirAll("log", 0755)
+	file := "log/zap-20231009-1000.log"
+	createMemFile(t, m, file)
+
+	t.Setenv(ChecksumKeyEnv, "secret")
+	if _, err := writeChecksum(file); err != nil {
+		t.Fatal(err)
+	}
+	// 删除.hmac、修改日志后写入不带密钥的.sha256
+	m.Remove(file + hmacExt)
+	w, _ := m.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	io.WriteString(w, "tampered")
+	w.Close()
+	digest, err := fileDigest(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ = m.OpenFile(file+checksumExt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	io.WriteString(w, digest+"  zap-20231009-1000.log\n")
+	w.Close()
+
+	if err := VerifyLogFile(file); err == nil || !strings.Contains(err.Error(), "no hmac") {
+		t.Fatalf("verify forged sha256: %v", err)
+	}
+}
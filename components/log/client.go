@@ -0,0 +1,99 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+)
+
+/*
+	@func: http客户端发出请求的日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// defaultRedactHeaders 默认脱敏的请求头
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// ClientLoggerConfig LoggingRoundTripper的可选配置
+type ClientLoggerConfig struct {
+	// LogQuery 为true时url中保留查询参数，默认去掉以免记录签名等敏感参数
+	LogQuery bool
+	// RequestHeaders 需要记录的请求头白名单，以request_headers对象输出
+	RequestHeaders []string
+	// ResponseHeaders 需要记录的响应头白名单，以response_headers对象输出
+	ResponseHeaders []string
+	// RedactHeaders 记录时需要脱敏的头，为空时使用Authorization、Proxy-Authorization、Cookie、Set-Cookie
+	RedactHeaders []string
+}
+
+// LoggingRoundTripper 包装base，记录发出请求的method、url、status和耗时，base为nil时使用http.DefaultTransport
+func LoggingRoundTripper(base http.RoundTripper) http.RoundTripper {
+	return LoggingRoundTripperWithConfig(base, ClientLoggerConfig{})
+}
+
+// LoggingRoundTripperWithConfig 按配置包装base
+func LoggingRoundTripperWithConfig(base http.RoundTripper, conf ClientLoggerConfig) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if len(conf.RedactHeaders) == 0 {
+		conf.RedactHeaders = defaultRedactHeaders
+	}
+	return &loggingRoundTripper{base: base, conf: conf}
+}
+
+type loggingRoundTripper struct {
+	base http.RoundTripper
+	conf ClientLoggerConfig
+}
+
+func (t *loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestID := RequestIDFromContext(req.Context())
+	if requestID != "" && req.Header.Get(RequestIDHeader) == "" {
+		// RoundTripper不能修改传入的请求
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	start := now()
+	resp, err := t.base.RoundTrip(req)
+	cost := now().Sub(start)
+
+	u := *req.URL
+	u.User = nil
+	if !t.conf.LogQuery {
+		u.RawQuery = ""
+	}
+	fields := []zap.Field{
+		zap.String("method", req.Method),
+		zap.String("url", u.String()),
+	}
+	if resp != nil {
+		fields = append(fields, zap.Int("status", resp.StatusCode))
+	}
+	fields = append(fields, zap.Duration("cost", cost))
+	if requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	if headers := t.pickHeaders(req.Header, t.conf.RequestHeaders); headers != nil {
+		fields = append(fields, zap.Object("request_headers", headers))
+	}
+	if resp != nil {
+		if headers := t.pickHeaders(resp.Header, t.conf.ResponseHeaders); headers != nil {
+			fields = append(fields, zap.Object("response_headers", headers))
+		}
+	}
+	// 请求失败与本包其他错误一样写入错误日志，受错误日志的限流和输出设置控制
+	if err != nil {
+		errLogger.Error("[http client] "+req.Method+" "+u.Host, append(fields, zap.Error(err))...)
+	} else if resp.StatusCode >= http.StatusInternalServerError {
+		LoggerFromContext(req.Context()).Warn("[http client] "+req.Method+" "+u.Host, fields...)
+	} else {
+		LoggerFromContext(req.Context()).Info("[http client] "+req.Method+" "+u.Host, fields...)
+	}
+	return resp, err
+}
+
+// pickHeaders 按白名单挑出存在的头，需要脱敏的头以redactedValue代替
+func (t *loggingRoundTripper) pickHeaders(header http.Header, names []string) headerValues {
+	return redactHeaders(pickHeaders(header, names), t.conf.RedactHeaders, 0)
+}
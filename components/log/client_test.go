@@ -0,0 +1,35 @@
+package log
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"testing"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestLoggingRoundTripperFailureGoesToErrorLog(t *testing.T) {
+	mainCore, mainLogs := observer.New(zapcore.DebugLevel)
+	errCore, errLogs := observer.New(zapcore.DebugLevel)
+	oldLogger, oldErr := logger, errLogger
+	logger, errLogger = zap.New(mainCore), zap.New(errCore)
+	defer func() { logger, errLogger = oldLogger, oldErr }()
+
+	client := &http.Client{Transport: LoggingRoundTripper(failingTransport{})}
+	if _, err := client.Get("http://upstream.example/api"); err == nil {
+		t.Fatal("expected transport error")
+	}
+	if errLogs.Len() != 1 || errLogs.All()[0].Level != zapcore.ErrorLevel {
+		t.Fatalf("error log entries = %v, want one Error", errLogs.All())
+	}
+	if mainLogs.Len() != 0 {
+		t.Fatalf("failure also written to the main logger: %v", mainLogs.All())
+	}
+}
@@ -0,0 +1,53 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	@func: 包内统一使用的时钟，测试中可替换为固定时间
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+type clockHolder struct {
+	zapcore.Clock
+}
+
+var currentClock atomic.Value
+
+func init() {
+	currentClock.Store(clockHolder{zapcore.DefaultClock})
+}
+
+// packageClock 委托给当前时钟，传给zap.WithClock后替换时钟对已创建的logger同样生效
+type packageClock struct{}
+
+func (packageClock) Now() time.Time {
+	return currentClock.Load().(clockHolder).Now()
+}
+
+func (packageClock) NewTicker(d time.Duration) *time.Ticker {
+	return currentClock.Load().(clockHolder).NewTicker(d)
+}
+
+// now 包内获取当前时间，日志时间和GinLogger的耗时都基于它
+func now() time.Time {
+	return packageClock{}.Now()
+}
+
+// Now 包内时钟的当前时间，子包计算耗时时使用，使SetClockForTest同样生效
+func Now() time.Time {
+	return now()
+}
+
+// SetClockForTest 仅供测试使用，替换包内时钟使日志时间和耗时字段可预期，返回恢复原时钟的函数
+func SetClockForTest(c zapcore.Clock) (restore func()) {
+	old := currentClock.Load().(clockHolder)
+	currentClock.Store(clockHolder{c})
+	return func() {
+		currentClock.Store(old)
+	}
+}
@@ -0,0 +1,257 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+	@func: zap_log的配置
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const (
+	EncodingConsole = "console"
+	EncodingJSON    = "json"
+	EncodingLogfmt  = "logfmt"
+	EncodingGCP     = "gcp"
+	// EncodingAuto 初始化时检测标准输出，为终端时使用console，为管道或文件时使用json
+	EncodingAuto = "auto"
+)
+
+const (
+	DurationSeconds = "seconds"
+	DurationMillis  = "millis"
+	DurationNanos   = "nanos"
+	DurationString  = "string"
+)
+
+const (
+	CallerShort   = "short"
+	CallerFull    = "full"
+	CallerPackage = "package"
+)
+
+const (
+	LevelCapital = "capital"
+	LevelLower   = "lower"
+	LevelNumber  = "number"
+)
+
+// validLevels 可配置的级别，用于错误提示
+const validLevels = "debug, info, warn, error, dpanic, panic, fatal"
+
+// parseLevel 解析配置的级别，不区分大小写，无效时返回带有该值及可用级别的错误
+func parseLevel(text string) (zapcore.Level, error) {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(strings.ToLower(strings.TrimSpace(text)))); err != nil {
+		return level, fmt.Errorf("log: invalid level %q, valid levels are %s", text, validLevels)
+	}
+	return level, nil
+}
+
+// LoggerConfig InitLoggerWithConfig的配置，零值字段使用默认值
+type LoggerConfig struct {
+	// Env 运行环境，prod和test会额外输出到日志文件
+	Env string
+	// Level 标准输出和日志文件的最低级别，如debug、info(不区分大小写)，无效时InitLoggerWithConfig返回错误，为空时标准输出为debug，日志文件prod为info、test为debug
+	Level string
+	// DisableStdout 为true时不输出到标准输出
+	DisableStdout bool
+	// DisableErrorStdout 为true时错误日志不再输出到标准输出，只写错误日志文件等，普通日志不受影响
+	DisableErrorStdout bool
+	// Sampling 设置后对普通日志按每秒相同消息采样，被丢弃的计入DroppedBySampling，错误日志不采样
+	Sampling *SamplingConfig
+	// Dedup 设置后合并连续相同的普通日志，只写入第一条，之后内容变化或超时后写入一条带repeated次数的日志
+	Dedup *DedupConfig
+	// StdoutEncoding 标准输出使用的编码，console、json、logfmt、gcp或auto，默认console
+	StdoutEncoding string
+	// FileEncoding 日志文件使用的编码，console、json、logfmt、gcp或auto，auto时按标准输出检测，默认console
+	FileEncoding string
+	// SeparateErrorLog 是否使用单独的错误日志logger及错误日志文件，默认true，
+	// 为false时错误日志直接写入普通日志的输出端，ErrorEncoding、ErrorRotate、DisableErrorStdout、ErrorRateLimit不再生效
+	SeparateErrorLog *bool
+	// ErrorEncoding 错误日志使用的编码，设置后错误日志的标准输出和文件都使用该编码，
+	// 为空时与StdoutEncoding、FileEncoding相同
+	ErrorEncoding string
+	// DurationEncoding 时长字段的编码方式，seconds、millis、nanos或string，默认seconds
+	DurationEncoding string
+	// LevelEncoding 级别的编码方式，capital为INFO、lower为info、number为zap的数值(Debug为-1、Info为0、Error为2)，默认capital
+	LevelEncoding string
+	// CallerEncoding 调用位置的编码方式，short为包名/文件:行号，full为完整路径，
+	// package为路径的最后CallerSegments段，默认short
+	CallerEncoding string
+	// CallerSegments CallerEncoding为package时保留的路径段数，如3为components/log/zap_log.go:42，<=0时为2
+	CallerSegments int
+	// LineEnding 每条日志的结尾，默认为zap的"\n"
+	LineEnding string
+	// ConsoleSeparator console编码下各部分之间的分隔符，默认为zap的"\t"
+	ConsoleSeparator string
+	// DevConsole 为true时console编码将含换行的字符串字段(如GinRecovery的stack)移到日志行之后逐行缩进输出，
+	// 便于本地阅读，json等其他编码不受影响
+	DevConsole bool
+	// SortFields 为true时每条日志的字段按key排序输出，便于生成稳定的日志，有一定开销默认关闭
+	SortFields bool
+	// EncoderConfig 自定义编码配置，设置后作为编码器的基础配置，
+	// 仅在其TimeKey为空时使用本包的时间编码，在其EncodeDuration为空时使用DurationEncoding
+	EncoderConfig *zapcore.EncoderConfig `json:"-"`
+	// Cores 额外加入普通日志的core，如NewElasticsearchCore、journald.NewCore创建的core，按各自的级别过滤
+	Cores []zapcore.Core `json:"-"`
+	// RecentLogs 大于0时在内存中保留最近RecentLogs条日志(含错误日志)，通过RecentLogs或RecentLogsHandler查看
+	RecentLogs int
+	// MaxFieldLength 消息和字符串字段的最大字节数，超出部分被截断，0表示不截断
+	MaxFieldLength int
+	// ErrorRateLimit 错误日志每秒最多输出的条数，超出的被丢弃，进入下一秒或最迟1秒后输出一条带被丢弃条数的汇总，
+	// DPanic及以上级别不受限制，0表示不限制
+	ErrorRateLimit int
+	// SuppressMessages 消息包含其中任一子串的日志被丢弃，用于屏蔽无法从源头关闭的已知无害日志
+	SuppressMessages []string
+	// SuppressPatterns 消息匹配其中任一正则表达式的日志被丢弃，初始化时编译，无效的表达式使初始化返回错误
+	SuppressPatterns []string
+	// NoSymlink 为true时不创建指向当前日志文件的软链接
+	NoSymlink bool
+	// SelfTest 为true时初始化后写入一条标记日志，并检查日志文件是否已创建且可写
+	SelfTest bool
+	// Rotate 日志文件的分割与保留设置，默认按分钟分割、保留7天，
+	// 普通日志在log目录，错误、审计、慢操作、panic日志分别在log/error、log/audit、log/slow、log/panic，各自按自己的设置清理
+	Rotate RotateConfig
+	// ErrorRotate 错误日志文件的分割与保留设置，未设置时与Rotate相同，部分设置时其余项取Rotate的值
+	ErrorRotate RotateConfig
+	// Audit 为true时启用独立的审计日志
+	Audit bool
+	// AuditRotate 审计日志的分割与保留设置，默认按天分割、保留一年
+	AuditRotate RotateConfig
+	// SlowLog 为true时启用独立的慢操作日志，通过SlowLogger写入
+	SlowLog bool
+	// SlowRotate 慢操作日志的分割与保留设置，未设置的项取Rotate的值
+	SlowRotate RotateConfig
+	// PanicLog 为true时启用独立的panic日志，GinRecovery、Go捕获的panic及Panic、Fatal在写入错误日志的同时写入该文件
+	PanicLog bool
+	// PanicRotate panic日志的分割与保留设置，默认按天分割、保留180天
+	PanicRotate RotateConfig
+}
+
+// SamplingConfig 每秒每条相同消息先记录Initial条，之后每Thereafter条记录一条
+type SamplingConfig struct {
+	Initial    int
+	Thereafter int
+}
+
+// DedupConfig 连续相同日志的合并设置
+type DedupConfig struct {
+	// Timeout 相同日志持续出现时，最长每隔Timeout写入一次repeated次数，默认1s
+	Timeout time.Duration
+	// IgnoreFields 为true时只比较级别和消息，默认还比较全部字段
+	IgnoreFields bool
+}
+
+// RotateConfig 日志文件的分割与保留设置，零值字段使用对应日志的默认值
+type RotateConfig struct {
+	// RotationTime 文件分割间隔
+	RotationTime time.Duration
+	// MaxAge 文件保留时长
+	MaxAge time.Duration
+	// MaxBackups 除当前文件外最多保留的文件数，超出时删除最旧的，0表示不限制
+	MaxBackups int
+	// DateDirs 为true时按日期分目录存放，如log/2023/10/09/zap-20231009-1504.log，切换时自动创建目录，
+	// 过期清理和MaxBackups会查找各日期目录
+	DateDirs bool
+	// Checksum 为true时在文件切换后为上一个文件写入校验文件，设置了LOG_CHECKSUM_KEY时为.hmac，否则为.sha256，
+	// 可通过VerifyLogFile校验文件是否被修改，常用于AuditRotate
+	Checksum bool
+}
+
+var (
+	defaultRotate      = RotateConfig{RotationTime: time.Minute, MaxAge: time.Hour * 24 * 7}
+	defaultAuditRotate = RotateConfig{RotationTime: time.Hour * 24, MaxAge: time.Hour * 24 * 365}
+	defaultPanicRotate = RotateConfig{RotationTime: time.Hour * 24, MaxAge: time.Hour * 24 * 180}
+)
+
+// withDefaults 用def补全未设置的分割设置
+func (rotate RotateConfig) withDefaults(def RotateConfig) RotateConfig {
+	if rotate.RotationTime <= 0 {
+		rotate.RotationTime = def.RotationTime
+	}
+	if rotate.MaxAge <= 0 {
+		rotate.MaxAge = def.MaxAge
+	}
+	if !rotate.DateDirs {
+		rotate.DateDirs = def.DateDirs
+	}
+	if !rotate.Checksum {
+		rotate.Checksum = def.Checksum
+	}
+	return rotate
+}
+
+// effectiveConfig 最近一次InitLoggerWithConfig或热更新实际生效的配置，由reloadMu保护
+var effectiveConfig LoggerConfig
+
+// EffectiveConfig 获取补全默认值后实际生效的配置
+func EffectiveConfig() LoggerConfig {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	return effectiveConfig
+}
+
+// separateErrorLog SeparateErrorLog未设置时为默认的true
+func (conf LoggerConfig) separateErrorLog() bool {
+	return conf.SeparateErrorLog == nil || *conf.SeparateErrorLog
+}
+
+// withDefaults 补全未设置的配置项
+func (conf LoggerConfig) withDefaults() LoggerConfig {
+	if conf.StdoutEncoding == "" {
+		conf.StdoutEncoding = EncodingConsole
+	}
+	if conf.FileEncoding == "" {
+		conf.FileEncoding = EncodingConsole
+	}
+	conf.StdoutEncoding = resolveAutoEncoding(conf.StdoutEncoding)
+	conf.FileEncoding = resolveAutoEncoding(conf.FileEncoding)
+	conf.ErrorEncoding = resolveAutoEncoding(conf.ErrorEncoding)
+	if conf.DurationEncoding == "" {
+		conf.DurationEncoding = DurationSeconds
+	}
+	if conf.LevelEncoding == "" {
+		conf.LevelEncoding = LevelCapital
+	}
+	if conf.CallerEncoding == "" {
+		conf.CallerEncoding = CallerShort
+	}
+	if conf.SeparateErrorLog == nil {
+		separate := true
+		conf.SeparateErrorLog = &separate
+	}
+	conf.Rotate = conf.Rotate.withDefaults(defaultRotate)
+	if conf.ErrorRotate == (RotateConfig{}) {
+		conf.ErrorRotate = conf.Rotate
+	} else {
+		conf.ErrorRotate = conf.ErrorRotate.withDefaults(conf.Rotate)
+	}
+	conf.AuditRotate = conf.AuditRotate.withDefaults(defaultAuditRotate)
+	conf.SlowRotate = conf.SlowRotate.withDefaults(conf.Rotate)
+	conf.PanicRotate = conf.PanicRotate.withDefaults(defaultPanicRotate)
+	return conf
+}
+
+// resolveAutoEncoding encoding为auto时按标准输出是否为终端选择console或json，其他值原样返回
+func resolveAutoEncoding(encoding string) string {
+	if encoding != EncodingAuto {
+		return encoding
+	}
+	if isTerminal(os.Stdout) {
+		return EncodingConsole
+	}
+	return EncodingJSON
+}
+
+// isTerminal 判断f是否为终端
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+	@func: 连接复用与keep-alive信息
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+type connRequestsKey struct{}
+
+// ConnContext 设置到http.Server.ConnContext上，用于统计每个连接处理过的请求数，
+// 使GinLogger能记录请求是否复用了连接
+func ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connRequestsKey{}, new(atomic.Int64))
+}
+
+// connRequestSeq 当前请求是所在连接的第几个请求，未设置ConnContext时返回0
+func connRequestSeq(c *gin.Context) int64 {
+	if n, ok := c.Request.Context().Value(connRequestsKey{}).(*atomic.Int64); ok {
+		return n.Add(1)
+	}
+	return 0
+}
+
+// connFields 连接相关的字段，seq为connRequestSeq的结果
+func connFields(c *gin.Context, seq int64) []zap.Field {
+	r := c.Request
+	fields := []zap.Field{
+		zap.Bool("conn_close", r.Close),
+		zap.Bool("keep_alive", keepAlive(r)),
+	}
+	if _, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		fields = append(fields, zap.String("remote_port", port))
+	}
+	if seq > 0 {
+		fields = append(fields, zap.Bool("conn_reused", seq > 1))
+	}
+	return fields
+}
+
+// keepAlive HTTP/1.1及以上默认保持连接，HTTP/1.0需显式声明
+func keepAlive(r *http.Request) bool {
+	if r.Close {
+		return false
+	}
+	if r.ProtoAtLeast(1, 1) {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get("Connection"), "keep-alive")
+}
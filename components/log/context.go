@@ -0,0 +1,104 @@
+package log
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"runtime/debug"
+)
+
+/*
+	@func: 通过context传递logger
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// RequestIDHeader 传递请求ID使用的请求头
+const RequestIDHeader = "X-Request-ID"
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+type levelKey struct{}
+
+// ContextWithLogger 将logger绑定到ctx上
+func ContextWithLogger(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFromContext 获取ctx上绑定的logger，未绑定时返回全局logger，
+// ctx上设置了ContextWithLevel时按该级别输出
+func LoggerFromContext(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return logger
+	}
+	l, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok {
+		l = logger
+	}
+	if level, ok := ctx.Value(levelKey{}).(zapcore.Level); ok {
+		return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return contextLevelCore{Core: core, level: level}
+		}))
+	}
+	return l
+}
+
+// ContextWithLevel 为ctx设置日志级别，通过LoggerFromContext记录的日志按该级别过滤而不受全局级别影响，
+// 如对指定请求设置为Debug以输出详细日志
+func ContextWithLevel(ctx context.Context, level zapcore.Level) context.Context {
+	return context.WithValue(ctx, levelKey{}, level)
+}
+
+// ContextWithRequestID 将请求ID绑定到ctx上，LoggingRoundTripper会将其带到发出的请求中
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext 获取ctx上绑定的请求ID，未绑定时返回空串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// Go 在新的goroutine中执行fn，fn继承ctx及其logger，panic会被recover并带上logger的字段记录
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	l := LoggerFromContext(ctx)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				fields := []zap.Field{
+					zap.Any("error", err),
+					zap.String("stack", string(debug.Stack())),
+				}
+				l.Error("[Recovery from panic in goroutine]", fields...)
+				panicLogger.Error("[Recovery from panic in goroutine]", fields...)
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// noopDone 未启用Debug时TimeBlock返回的函数
+func noopDone() {}
+
+// TimeBlock 用ctx上的logger以Debug记录代码块的耗时，用法为defer TimeBlock(ctx, "db.Query")()，
+// 未启用Debug时不获取时间直接返回空函数
+func TimeBlock(ctx context.Context, name string) func() {
+	l := LoggerFromContext(ctx)
+	if !l.Core().Enabled(zapcore.DebugLevel) {
+		return noopDone
+	}
+	start := now()
+	return func() {
+		l.WithOptions(zap.AddCallerSkip(1)).Debug("[time block] "+name,
+			zap.String("block", name),
+			zap.Duration("cost", now().Sub(start)),
+		)
+	}
+}
@@ -0,0 +1,453 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+	@func: 包装zapcore.Core的各类core
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// forceWrite 标记字段的值，带有该字段的日志跳过各级core的级别检查直接写入
+type forceWrite struct{}
+
+// forceWriteField 不输出任何内容，仅用于标记ContextWithLevel放行的日志
+var forceWriteField = zapcore.Field{Type: zapcore.SkipType, Interface: forceWrite{}}
+
+// hasForceWrite 判断fields中是否带有forceWriteField
+func hasForceWrite(fields []zapcore.Field) bool {
+	for _, f := range fields {
+		if f.Type == zapcore.SkipType && f.Interface == (forceWrite{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeChecked 经由core.Check写入，使Tee中的各个子core按自身级别过滤，
+// 带有forceWriteField时直接写入
+func writeChecked(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) error {
+	if hasForceWrite(fields) {
+		return core.Write(ent, fields)
+	}
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// newSamplerCore 每秒每条相同消息先记录first条，之后每thereafter条记录一条，被丢弃的计入DroppedBySampling
+func newSamplerCore(core zapcore.Core, first, thereafter int) zapcore.Core {
+	return zapcore.NewSamplerWithOptions(core, time.Second, first, thereafter,
+		zapcore.SamplerHook(func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped > 0 {
+				stats.droppedBySampling.Add(1)
+			}
+		}))
+}
+
+// messageFilter 按子串和正则表达式匹配需要丢弃的消息
+type messageFilter struct {
+	substrings []string
+	patterns   []*regexp.Regexp
+}
+
+// newMessageFilter 编译patterns，substrings和patterns都为空时返回nil
+func newMessageFilter(substrings, patterns []string) (*messageFilter, error) {
+	if len(substrings) == 0 && len(patterns) == 0 {
+		return nil, nil
+	}
+	f := &messageFilter{substrings: substrings}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("log: invalid suppress pattern %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+func (f *messageFilter) match(msg string) bool {
+	for _, s := range f.substrings {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	for _, re := range f.patterns {
+		if re.MatchString(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterCore 丢弃消息与filter匹配的日志
+type filterCore struct {
+	zapcore.Core
+	filter *messageFilter
+}
+
+func (c filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return filterCore{Core: c.Core.With(fields), filter: c.filter}
+}
+
+func (c filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.filter.match(ent.Message) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c filterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.filter.match(ent.Message) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// truncateCore 截断超过max字节的消息和字符串字段
+type truncateCore struct {
+	zapcore.Core
+	max int
+}
+
+func newTruncateCore(core zapcore.Core, max int) zapcore.Core {
+	return &truncateCore{Core: core, max: max}
+}
+
+func (c *truncateCore) With(fields []zapcore.Field) zapcore.Core {
+	return &truncateCore{Core: c.Core.With(c.truncateFields(fields)), max: c.max}
+}
+
+func (c *truncateCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *truncateCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = truncateString(ent.Message, c.max)
+	return writeChecked(c.Core, ent, c.truncateFields(fields))
+}
+
+func (c *truncateCore) truncateFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		var truncated zapcore.Field
+		switch {
+		case f.Type == zapcore.StringType && len(f.String) > c.max:
+			truncated = f
+			truncated.String = truncateString(f.String, c.max)
+		case f.Type == zapcore.ByteStringType && len(f.Interface.([]byte)) > c.max:
+			truncated = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: truncateString(string(f.Interface.([]byte)), c.max)}
+		default:
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]zapcore.Field, 0, len(fields)), fields[:i]...)
+		}
+		out = append(out, truncated)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// truncateString 超过max字节时在字符边界处截断，并注明截掉的字节数
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", s[:cut], len(s)-cut)
+}
+
+// rateCapCore 每秒最多写入max条，超出的计数后丢弃，进入下一秒或rateCapFlushDelay后写入一条汇总，高于Error的级别不受限制
+type rateCapCore struct {
+	zapcore.Core
+	state *rateCapState
+}
+
+// rateCapFlushDelay 丢弃开始后最迟经过该时长写入汇总，即使之后不再有错误日志
+const rateCapFlushDelay = time.Second
+
+// rateCapState 由With派生出的各个core共享，窗口、计数和丢弃数由mu一起保护
+type rateCapState struct {
+	// core 用于写入汇总，不带With添加的字段
+	core       zapcore.Core
+	max        int64
+	delay      time.Duration
+	mu         sync.Mutex
+	window     int64
+	count      int64
+	suppressed uint64
+	timer      *time.Timer
+}
+
+func newRateCapCore(core zapcore.Core, max int) zapcore.Core {
+	return &rateCapCore{Core: core, state: &rateCapState{core: core, max: int64(max), delay: rateCapFlushDelay}}
+}
+
+func (c *rateCapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateCapCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *rateCapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// DPanic、Panic、Fatal之后进程可能panic或退出，不计数也不丢弃
+	if ent.Level > zapcore.ErrorLevel {
+		return c.Core.Check(ent, ce)
+	}
+	if !c.Enabled(ent.Level) || !c.state.allow(ent.Time) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *rateCapCore) Sync() error {
+	c.state.flush()
+	return c.Core.Sync()
+}
+
+// allow 计入t所在的一秒，超出max时记为丢弃，进入新的一秒时先写入上一秒的汇总
+func (s *rateCapState) allow(t time.Time) bool {
+	s.mu.Lock()
+	var pending uint64
+	if sec := t.Unix(); sec != s.window {
+		s.window, s.count = sec, 0
+		pending = s.takeLocked()
+	}
+	s.count++
+	allowed := s.count <= s.max
+	if !allowed {
+		s.suppressed++
+		if s.timer == nil {
+			s.timer = time.AfterFunc(s.delay, s.flush)
+		}
+	}
+	s.mu.Unlock()
+	s.writeSummary(pending, t)
+	return allowed
+}
+
+// flush 立即写入尚未写出的汇总，由定时器和Sync调用
+func (s *rateCapState) flush() {
+	s.mu.Lock()
+	pending := s.takeLocked()
+	s.mu.Unlock()
+	s.writeSummary(pending, now())
+}
+
+// takeLocked 取出丢弃数并停止定时器
+func (s *rateCapState) takeLocked() uint64 {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	n := s.suppressed
+	s.suppressed = 0
+	return n
+}
+
+// writeSummary 有被丢弃的日志时写入汇总，汇总本身不受限制
+func (s *rateCapState) writeSummary(n uint64, t time.Time) {
+	if n == 0 {
+		return
+	}
+	writeChecked(s.core, zapcore.Entry{
+		Level:   zapcore.ErrorLevel,
+		Time:    t,
+		Message: "[log] error lines suppressed by rate limit",
+	}, []zapcore.Field{
+		zap.Uint64("suppressed", n),
+		zap.Int64("limit_per_second", s.max),
+	})
+}
+
+// dedupCore 连续相同的日志只写入第一条，内容变化或超时后写入一条带repeated次数的日志
+type dedupCore struct {
+	zapcore.Core
+	context []zapcore.Field
+	state   *dedupState
+}
+
+// dedupState 由With派生出的各个core共享，只比较相邻的两条
+type dedupState struct {
+	mu           sync.Mutex
+	timeout      time.Duration
+	ignoreFields bool
+	keyEncoder   zapcore.Encoder
+	timer        *time.Timer
+
+	key     string
+	core    zapcore.Core
+	entry   zapcore.Entry
+	fields  []zapcore.Field
+	repeats int
+}
+
+func newDedupCore(core zapcore.Core, conf DedupConfig) zapcore.Core {
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second
+	}
+	return &dedupCore{Core: core, state: &dedupState{
+		timeout:      conf.Timeout,
+		ignoreFields: conf.IgnoreFields,
+		keyEncoder:   zapcore.NewJSONEncoder(zapcore.EncoderConfig{}),
+	}}
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	context := append(append([]zapcore.Field(nil), c.context...), fields...)
+	return &dedupCore{Core: c.Core.With(fields), context: context, state: c.state}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := c.state
+	key := s.dedupKey(ent, c.context, fields)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.core != nil && key == s.key {
+		s.repeats++
+		s.entry.Time = ent.Time
+		if s.repeats == 1 {
+			s.timer = time.AfterFunc(s.timeout, s.flushRepeats)
+		}
+		return nil
+	}
+	s.writeRepeatsLocked()
+	s.key, s.core, s.entry, s.fields = key, c.Core, ent, fields
+	return writeChecked(c.Core, ent, fields)
+}
+
+func (c *dedupCore) Sync() error {
+	c.state.flushRepeats()
+	return c.Core.Sync()
+}
+
+// dedupKey 级别、消息以及(未忽略时)全部字段编码后作为比较的key
+func (s *dedupState) dedupKey(ent zapcore.Entry, context, fields []zapcore.Field) string {
+	key := ent.Level.String() + "\x00" + ent.Message
+	if s.ignoreFields {
+		return key
+	}
+	buf, err := s.keyEncoder.EncodeEntry(zapcore.Entry{}, append(append([]zapcore.Field(nil), context...), fields...))
+	if err != nil {
+		return key
+	}
+	key += "\x00" + buf.String()
+	buf.Free()
+	return key
+}
+
+func (s *dedupState) flushRepeats() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeRepeatsLocked()
+}
+
+// writeRepeatsLocked 有被合并的日志时写入一条带repeated次数的日志
+func (s *dedupState) writeRepeatsLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.repeats == 0 {
+		return
+	}
+	fields := append(append([]zapcore.Field(nil), s.fields...), zap.Int("repeated", s.repeats))
+	s.repeats = 0
+	writeChecked(s.core, s.entry, fields)
+}
+
+// reloadRoot 热更新时整体替换的core，gen每次替换加一
+type reloadRoot struct {
+	current atomic.Pointer[reloadGen]
+}
+
+// reloadGen 某一代core，With派生的core按gen判断缓存是否过期
+type reloadGen struct {
+	gen  uint64
+	core zapcore.Core
+}
+
+// reloadableCore 全局logger使用的core，热更新时替换其背后的core而不重建logger，
+// 已通过With派生的logger在下一次写入时切换到新的core
+type reloadableCore struct {
+	root   *reloadRoot
+	fields []zapcore.Field
+	// cached 当前代的core加上fields，避免每次写入都调用With
+	cached atomic.Pointer[reloadGen]
+}
+
+func newReloadableCore(core zapcore.Core) *reloadableCore {
+	root := &reloadRoot{}
+	root.current.Store(&reloadGen{core: core})
+	return &reloadableCore{root: root}
+}
+
+// swap 替换背后的core，返回被替换的core
+func (c *reloadableCore) swap(core zapcore.Core) zapcore.Core {
+	old := c.root.current.Load()
+	c.root.current.Store(&reloadGen{gen: old.gen + 1, core: core})
+	return old.core
+}
+
+// get 当前代的core，有fields时使用或更新缓存
+func (c *reloadableCore) get() zapcore.Core {
+	cur := c.root.current.Load()
+	if len(c.fields) == 0 {
+		return cur.core
+	}
+	if cached := c.cached.Load(); cached != nil && cached.gen == cur.gen {
+		return cached.core
+	}
+	derived := &reloadGen{gen: cur.gen, core: cur.core.With(c.fields)}
+	c.cached.Store(derived)
+	return derived.core
+}
+
+func (c *reloadableCore) Enabled(lvl zapcore.Level) bool {
+	return c.get().Enabled(lvl)
+}
+
+func (c *reloadableCore) With(fields []zapcore.Field) zapcore.Core {
+	return &reloadableCore{root: c.root, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *reloadableCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.get().Check(ent, ce)
+}
+
+func (c *reloadableCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.get().Write(ent, fields)
+}
+
+func (c *reloadableCore) Sync() error {
+	return c.get().Sync()
+}
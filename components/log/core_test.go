@@ -0,0 +1,273 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// observedLines 每条日志的消息及repeated、suppressed字段，便于整体比较
+func observedLines(logs *observer.ObservedLogs) []string {
+	var lines []string
+	for _, e := range logs.All() {
+		line := e.Message
+		ctx := e.ContextMap()
+		for _, key := range []string{"repeated", "suppressed"} {
+			if v, ok := ctx[key]; ok {
+				line += fmt.Sprintf(" %s=%v", key, v)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+type testEntry struct {
+	msg    string
+	fields []zap.Field
+}
+
+func TestDedupCore(t *testing.T) {
+	cases := []struct {
+		name    string
+		conf    DedupConfig
+		entries []testEntry
+		want    []string
+	}{
+		{
+			name:    "distinct",
+			entries: []testEntry{{msg: "a"}, {msg: "b"}, {msg: "a"}},
+			want:    []string{"a", "b", "a"},
+		},
+		{
+			name:    "repeats flushed when content changes",
+			entries: []testEntry{{msg: "a"}, {msg: "a"}, {msg: "a"}, {msg: "b"}},
+			want:    []string{"a", "a repeated=2", "b"},
+		},
+		{
+			name:    "single repeat",
+			entries: []testEntry{{msg: "a"}, {msg: "a"}, {msg: "b"}, {msg: "b"}, {msg: "c"}},
+			want:    []string{"a", "a repeated=1", "b", "b repeated=1", "c"},
+		},
+		{
+			name: "fields compared by default",
+			entries: []testEntry{
+				{msg: "a", fields: []zap.Field{zap.Int("n", 1)}},
+				{msg: "a", fields: []zap.Field{zap.Int("n", 2)}},
+			},
+			want: []string{"a", "a"},
+		},
+		{
+			name: "fields ignored",
+			conf: DedupConfig{IgnoreFields: true},
+			entries: []testEntry{
+				{msg: "a", fields: []zap.Field{zap.Int("n", 1)}},
+				{msg: "a", fields: []zap.Field{zap.Int("n", 2)}},
+				{msg: "b"},
+			},
+			want: []string{"a", "a repeated=1", "b"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			if tc.conf.Timeout == 0 {
+				tc.conf.Timeout = time.Hour
+			}
+			l := zap.New(newDedupCore(core, tc.conf))
+			for _, e := range tc.entries {
+				l.Info(e.msg, e.fields...)
+			}
+			if got := observedLines(logs); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDedupCoreFlushesRepeatsAfterTimeout(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newDedupCore(core, DedupConfig{Timeout: 20 * time.Millisecond}))
+	for i := 0; i < 3; i++ {
+		l.Info("a")
+	}
+	deadline := time.Now().Add(time.Second)
+	for logs.Len() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("repeats not flushed after timeout: %q", observedLines(logs))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// 超时写出后再次出现的相同日志重新开始计数
+	l.Info("a")
+	l.Sync()
+	want := []string{"a", "a repeated=2", "a repeated=1"}
+	if got := observedLines(logs); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDedupCoreSyncFlushesRepeats(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newDedupCore(core, DedupConfig{Timeout: time.Hour})).With(zap.String("svc", "api"))
+	l.Warn("a")
+	l.Warn("a")
+	l.Sync()
+	want := []string{"a", "a repeated=1"}
+	if got := observedLines(logs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if e := logs.All()[1]; e.Level != zapcore.WarnLevel || e.ContextMap()["svc"] != "api" {
+		t.Errorf("repeat summary lost level or context: %v %v", e.Level, e.ContextMap())
+	}
+}
+
+func TestRateCapCoreSummaryAcrossWindows(t *testing.T) {
+	base := time.Date(2023, 10, 9, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		max     int
+		offsets []time.Duration
+		want    []string
+	}{
+		{
+			name:    "under limit",
+			max:     3,
+			offsets: []time.Duration{0, 100 * time.Millisecond, time.Second},
+			want:    []string{"e0", "e1", "e2"},
+		},
+		{
+			name:    "summary at next window",
+			max:     2,
+			offsets: []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 900 * time.Millisecond, 1200 * time.Millisecond},
+			want:    []string{"e0", "e1", "[log] error lines suppressed by rate limit suppressed=2", "e4"},
+		},
+		{
+			name:    "count resets each window",
+			max:     1,
+			offsets: []time.Duration{0, 10 * time.Millisecond, time.Second, 1010 * time.Millisecond, 1020 * time.Millisecond, 5 * time.Second},
+			want: []string{
+				"e0",
+				"[log] error lines suppressed by rate limit suppressed=1", "e2",
+				"[log] error lines suppressed by rate limit suppressed=2", "e5",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			clock := &fixedClock{}
+			l := zap.New(newRateCapCore(core, tc.max), zap.WithClock(clock)).With(zap.String("svc", "api"))
+			for i, offset := range tc.offsets {
+				clock.t = base.Add(offset)
+				l.Error(fmt.Sprintf("e%d", i))
+			}
+			if got := observedLines(logs); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			for _, e := range logs.All() {
+				if _, ok := e.ContextMap()["limit_per_second"]; ok {
+					if _, ok := e.ContextMap()["svc"]; ok {
+						t.Errorf("summary carries With fields of the logger that triggered it: %v", e.ContextMap())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRateCapCoreSyncWritesPendingSummary(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	clock := &fixedClock{t: time.Date(2023, 10, 9, 10, 0, 0, 0, time.UTC)}
+	defer SetClockForTest(clock)()
+	l := zap.New(newRateCapCore(core, 1), zap.WithClock(clock))
+	for i := 0; i < 4; i++ {
+		l.Error("e")
+	}
+	l.Sync()
+	l.Sync()
+	want := []string{"e", "[log] error lines suppressed by rate limit suppressed=3"}
+	if got := observedLines(logs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := logs.All()[1].ContextMap()["limit_per_second"]; got != int64(1) {
+		t.Errorf("limit_per_second = %v, want 1", got)
+	}
+}
+
+func TestRateCapCoreNeverDropsPanicOrFatal(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	clock := &fixedClock{t: time.Date(2023, 10, 9, 10, 0, 0, 0, time.UTC)}
+	l := zap.New(newRateCapCore(core, 1), zap.WithClock(clock), zap.WithFatalHook(zapcore.WriteThenGoexit))
+	l.Error("e1")
+	l.Error("e2")
+	func() {
+		defer func() { recover() }()
+		l.Panic("the panic")
+	}()
+	l.DPanic("the dpanic")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Fatal("the fatal")
+	}()
+	<-done
+	for _, msg := range []string{"the panic", "the dpanic", "the fatal"} {
+		if logs.FilterMessage(msg).Len() != 1 {
+			t.Errorf("%q was dropped by the rate cap: %q", msg, observedLines(logs))
+		}
+	}
+	if logs.FilterMessage("e2").Len() != 0 {
+		t.Errorf("second error within the window was not suppressed")
+	}
+}
+
+func TestRateCapCoreFlushesSummaryWhenStormEnds(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	c := newRateCapCore(core, 1).(*rateCapCore)
+	c.state.delay = 20 * time.Millisecond
+	l := zap.New(c)
+	for i := 0; i < 3; i++ {
+		l.Error("e")
+	}
+	// 之后不再有错误日志，也不调用Sync
+	deadline := time.Now().Add(time.Second)
+	for logs.FilterMessage("[log] error lines suppressed by rate limit").Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("summary not written after the storm ended: %q", observedLines(logs))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := logs.All()[logs.Len()-1].ContextMap()["suppressed"]; got != uint64(2) {
+		t.Errorf("suppressed = %v, want 2", got)
+	}
+}
+
+func TestRateCapCoreConcurrentWindowReset(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	clock := &fixedClock{t: time.Date(2023, 10, 9, 10, 0, 0, 0, time.UTC)}
+	l := zap.New(newRateCapCore(core, 100), zap.WithClock(clock))
+	l.Error("warmup")
+	clock.t = clock.t.Add(time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				l.Error("e")
+			}
+		}()
+	}
+	wg.Wait()
+	l.Sync()
+	// 新的一秒内80条未超过上限，不应有任何丢弃
+	if n := logs.FilterMessage("e").Len(); n != 80 {
+		t.Fatalf("wrote %d of 80 entries: %q", n, observedLines(logs))
+	}
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"sync/atomic"
+)
+
+/*
+	@func: 请求级的数据库查询和缓存计数
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+type requestCountersKey struct{}
+
+// requestCounters 一个请求内累计的计数，handler中的goroutine也可能累加
+type requestCounters struct {
+	dbQueries   atomic.Int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+}
+
+// installRequestCounters 在请求的context上安装计数器
+func installRequestCounters(c *gin.Context) *requestCounters {
+	counters := &requestCounters{}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestCountersKey{}, counters))
+	return counters
+}
+
+func countersFromContext(ctx context.Context) *requestCounters {
+	if ctx == nil {
+		return nil
+	}
+	counters, _ := ctx.Value(requestCountersKey{}).(*requestCounters)
+	return counters
+}
+
+// IncrQueryCount 累加ctx所属请求的数据库查询次数，ctx需来自c.Request.Context()，未开启计数时忽略
+func IncrQueryCount(ctx context.Context) {
+	if counters := countersFromContext(ctx); counters != nil {
+		counters.dbQueries.Add(1)
+	}
+}
+
+// IncrCacheHit 累加ctx所属请求的缓存命中次数
+func IncrCacheHit(ctx context.Context) {
+	if counters := countersFromContext(ctx); counters != nil {
+		counters.cacheHits.Add(1)
+	}
+}
+
+// IncrCacheMiss 累加ctx所属请求的缓存未命中次数
+func IncrCacheMiss(ctx context.Context) {
+	if counters := countersFromContext(ctx); counters != nil {
+		counters.cacheMisses.Add(1)
+	}
+}
+
+func (r *requestCounters) fields() []zap.Field {
+	return []zap.Field{
+		zap.Int64("db_queries", r.dbQueries.Load()),
+		zap.Int64("cache_hits", r.cacheHits.Load()),
+		zap.Int64("cache_misses", r.cacheMisses.Load()),
+	}
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"strings"
+)
+
+/*
+	@func: 开发时的console编码，多行字段缩进后单独输出
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// devConsoleIndent 多行字段每行的缩进
+const devConsoleIndent = "    "
+
+// devConsoleEncoder 将单条日志中含换行的字符串字段(如stack)从行内移出，在日志行之后逐行缩进输出
+type devConsoleEncoder struct {
+	zapcore.Encoder
+	lineEnding string
+}
+
+func newDevConsoleEncoder(encoder zapcore.Encoder, lineEnding string) zapcore.Encoder {
+	if lineEnding == "" {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+	return devConsoleEncoder{Encoder: encoder, lineEnding: lineEnding}
+}
+
+func (e devConsoleEncoder) Clone() zapcore.Encoder {
+	return devConsoleEncoder{Encoder: e.Encoder.Clone(), lineEnding: e.lineEnding}
+}
+
+func (e devConsoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	var inline, multiline []zapcore.Field
+	for _, f := range fields {
+		if f.Type == zapcore.StringType && strings.Contains(f.String, "\n") {
+			multiline = append(multiline, f)
+			continue
+		}
+		inline = append(inline, f)
+	}
+	buf, err := e.Encoder.EncodeEntry(ent, inline)
+	if err != nil || len(multiline) == 0 {
+		return buf, err
+	}
+	line := strings.TrimSuffix(buf.String(), e.lineEnding)
+	buf.Reset()
+	buf.AppendString(line)
+	for _, f := range multiline {
+		buf.AppendString(e.lineEnding + devConsoleIndent + f.Key + ":")
+		for _, line := range strings.Split(strings.TrimRight(f.String, "\r\n"), "\n") {
+			buf.AppendString(e.lineEnding + devConsoleIndent + devConsoleIndent + strings.TrimSuffix(line, "\r"))
+		}
+	}
+	buf.AppendString(e.lineEnding)
+	return buf, nil
+}
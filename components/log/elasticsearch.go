@@ -0,0 +1,226 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+	@func: 通过bulk接口直接写入Elasticsearch/OpenSearch
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// ElasticsearchConfig Elasticsearch输出的配置，零值字段使用默认值
+type ElasticsearchConfig struct {
+	// URL 集群地址，如http://127.0.0.1:9200
+	URL string
+	// Index 索引名，从2006开始的部分按日志时间(UTC)格式化，如logs-2006.01.02，默认logs-2006.01.02
+	Index string
+	// Username、Password 设置后使用Basic认证
+	Username string
+	Password string
+	// APIKey 设置后使用ApiKey认证，优先于Basic认证
+	APIKey string
+	// Level 写入的最低级别，零值即info
+	Level zapcore.Level
+	// BatchSize 每次bulk请求最多的条数，默认500
+	BatchSize int
+	// FlushInterval 未攒满一批时的最长发送间隔，默认1s
+	FlushInterval time.Duration
+	// QueueSize 等待发送的日志队列长度，队列满时丢弃并计入DroppedBySink，默认10000
+	QueueSize int
+	// MaxRetries 每批最多重试次数，默认3
+	MaxRetries int
+	// RetryBackoff 首次重试前的等待时间，之后每次翻倍，默认1s
+	RetryBackoff time.Duration
+	// Timeout 单次bulk请求的超时，默认10s
+	Timeout time.Duration
+}
+
+// ElasticsearchCore 以JSON批量写入Elasticsearch的zapcore.Core，通过LoggerConfig.Cores加入logger
+type ElasticsearchCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	sink *esSink
+}
+
+// esDoc 一条待写入的日志
+type esDoc struct {
+	index string
+	body  []byte
+}
+
+// esSink 由With派生出的各个core共享的发送队列
+type esSink struct {
+	*batchSink[esDoc]
+	conf   ElasticsearchConfig
+	client *http.Client
+}
+
+// NewElasticsearchCore 创建Elasticsearch输出并启动发送goroutine，写入不会阻塞，退出前需调用Close
+func NewElasticsearchCore(conf ElasticsearchConfig) (*ElasticsearchCore, error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("log: elasticsearch url is required")
+	}
+	if conf.Index == "" {
+		conf.Index = "logs-2006.01.02"
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 500
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 10000
+	}
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = time.Second
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "@timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeDuration = zapcore.MillisDurationEncoder
+	encoderConfig.LineEnding = "\n"
+	s := &esSink{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+	s.batchSink = newBatchSink(batchSinkConfig{
+		name:          "elasticsearch",
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		queueSize:     conf.QueueSize,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+	}, encodeBulk, s.sendOnce)
+	return &ElasticsearchCore{
+		LevelEnabler: conf.Level,
+		enc:          zapcore.NewJSONEncoder(encoderConfig),
+		sink:         s,
+	}, nil
+}
+
+func (c *ElasticsearchCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &ElasticsearchCore{LevelEnabler: c.LevelEnabler, enc: enc, sink: c.sink}
+}
+
+func (c *ElasticsearchCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ElasticsearchCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	body := bytes.TrimRight(buf.Bytes(), "\n")
+	doc := esDoc{index: c.sink.indexName(ent.Time), body: append([]byte(nil), body...)}
+	buf.Free()
+	c.sink.enqueue(doc)
+	return nil
+}
+
+// Sync 等待队列中已有的日志发送完成
+func (c *ElasticsearchCore) Sync() error {
+	c.sink.sync()
+	return nil
+}
+
+// Close 停止发送goroutine，发送完队列中剩余的日志后返回
+func (c *ElasticsearchCore) Close() error {
+	c.sink.close()
+	return nil
+}
+
+// indexName 按日志时间生成索引名
+func (s *esSink) indexName(t time.Time) string {
+	i := strings.Index(s.conf.Index, "2006")
+	if i < 0 {
+		return s.conf.Index
+	}
+	return s.conf.Index[:i] + t.UTC().Format(s.conf.Index[i:])
+}
+
+// encodeBulk 将一批日志编码为bulk请求的NDJSON
+func encodeBulk(batch []esDoc) ([]byte, error) {
+	var body bytes.Buffer
+	for _, doc := range batch {
+		meta, err := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": doc.index}})
+		if err != nil {
+			return nil, err
+		}
+		body.Write(meta)
+		body.WriteByte('\n')
+		body.Write(doc.body)
+		body.WriteByte('\n')
+	}
+	return body.Bytes(), nil
+}
+
+// sendOnce 发送一次bulk请求，返回是否值得重试，部分条目失败时只计数不重试
+func (s *esSink) sendOnce(body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.conf.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.conf.URL, "/")+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.conf.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.conf.APIKey)
+	} else if s.conf.Username != "" {
+		req.SetBasicAuth(s.conf.Username, s.conf.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		io.Copy(io.Discard, resp.Body)
+		return true, fmt.Errorf("log: elasticsearch bulk returned %s", resp.Status)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("log: elasticsearch bulk returned %s: %s", resp.Status, msg)
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || !result.Errors {
+		return false, nil
+	}
+	var failed uint64
+	for _, item := range result.Items {
+		for _, r := range item {
+			if r.Status >= http.StatusBadRequest {
+				failed++
+			}
+		}
+	}
+	stats.droppedBySink.Add(failed)
+	return false, nil
+}
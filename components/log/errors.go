@@ -0,0 +1,38 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+)
+
+/*
+	@func: 记录错误的辅助函数
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// LogAndReturn 通过errLogger记录err后原样返回，err为nil时不记录直接返回nil
+func LogAndReturn(msg string, err error, fields ...zap.Field) error {
+	if err == nil {
+		return nil
+	}
+	errLogger.WithOptions(zap.AddCallerSkip(1)).Error(msg, append(fields, zap.Error(err))...)
+	return err
+}
+
+// LogAndWrap 通过errLogger记录err后返回以msg包装的错误，可用errors.Is/As判断原错误，err为nil时返回nil
+func LogAndWrap(msg string, err error, fields ...zap.Field) error {
+	if err == nil {
+		return nil
+	}
+	errLogger.WithOptions(zap.AddCallerSkip(1)).Error(msg, append(fields, zap.Error(err))...)
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// LogMismatch 以Error记录期望值与实际值不一致，两者分别以expected、actual字段输出，用于测试和断言失败时的诊断
+func LogMismatch(msg string, expected, actual interface{}, fields ...zap.Field) {
+	errLogger.WithOptions(zap.AddCallerSkip(1)).Error(msg, append([]zap.Field{
+		zap.Any("expected", expected),
+		zap.Any("actual", actual),
+	}, fields...)...)
+}
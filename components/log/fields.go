@@ -0,0 +1,73 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"sync"
+)
+
+/*
+	@func: 常用的zap.Field构造
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// lazyValue 编码时才调用fn计算字段值，同一条日志写入Tee的多个core时只计算一次
+type lazyValue struct {
+	key   string
+	fn    func() interface{}
+	once  sync.Once
+	value interface{}
+}
+
+func (v *lazyValue) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	v.once.Do(func() {
+		v.value = v.fn()
+	})
+	return enc.AddReflected(v.key, v.value)
+}
+
+// LazyField 延迟计算的字段，只有日志真正输出时才调用fn，级别未开启时不产生计算开销，
+// fn最多调用一次，结果由所有输出共用，用于单条日志，传给With时会立即计算
+func LazyField(key string, fn func() interface{}) zap.Field {
+	return zap.Inline(&lazyValue{key: key, fn: fn})
+}
+
+var (
+	fieldEncodersMu sync.RWMutex
+	fieldEncoders   = map[reflect.Type]func(v interface{}) zapcore.ObjectMarshaler{}
+)
+
+// RegisterFieldEncoder 为类型T注册统一的编码方式，之后通过Field记录T或*T类型的值时使用fn的结果，
+// 用于金额、自定义ID等zap.Any输出不理想的领域类型，重复注册时覆盖之前的
+func RegisterFieldEncoder[T any](fn func(v T) zapcore.ObjectMarshaler) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	fieldEncodersMu.Lock()
+	defer fieldEncodersMu.Unlock()
+	fieldEncoders[typ] = func(v interface{}) zapcore.ObjectMarshaler {
+		return fn(v.(T))
+	}
+}
+
+// Field 按v的类型使用RegisterFieldEncoder注册的编码，v为已注册类型的非nil指针时解引用后编码，
+// 未注册的类型回退到zap.Any
+func Field(key string, v interface{}) zap.Field {
+	if v == nil {
+		return zap.Any(key, v)
+	}
+	fieldEncodersMu.RLock()
+	defer fieldEncodersMu.RUnlock()
+	if len(fieldEncoders) == 0 {
+		return zap.Any(key, v)
+	}
+	if fn, ok := fieldEncoders[reflect.TypeOf(v)]; ok {
+		return zap.Object(key, fn(v))
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		if fn, ok := fieldEncoders[rv.Type().Elem()]; ok {
+			return zap.Object(key, fn(rv.Elem().Interface()))
+		}
+	}
+	return zap.Any(key, v)
+}
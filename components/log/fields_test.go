@@ -0,0 +1,36 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"testing"
+)
+
+func TestLazyFieldEvaluatedOncePerEntry(t *testing.T) {
+	first, firstLogs := observer.New(zapcore.InfoLevel)
+	second, secondLogs := observer.New(zapcore.InfoLevel)
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	third := zapcore.NewCore(enc, zapcore.AddSync(io.Discard), zapcore.InfoLevel)
+	l := zap.New(zapcore.NewTee(first, second, third))
+
+	calls := 0
+	l.Info("lazy", LazyField("expensive", func() interface{} {
+		calls++
+		return calls
+	}))
+	if calls != 1 {
+		t.Fatalf("fn called %d times for one entry on three cores, want 1", calls)
+	}
+	for _, logs := range []*observer.ObservedLogs{firstLogs, secondLogs} {
+		if got := logs.All()[0].ContextMap()["expensive"]; got != 1 {
+			t.Errorf("expensive = %v, want 1", got)
+		}
+	}
+
+	l.Debug("disabled", LazyField("expensive", func() interface{} {
+		t.Error("fn called for a disabled level")
+		return nil
+	}))
+}
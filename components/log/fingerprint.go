@@ -0,0 +1,60 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+/*
+	@func: 计算请求指纹，用于发现重复提交和重试
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// defaultMaxFingerprintBody 指纹默认读取的最大请求体字节数
+const defaultMaxFingerprintBody = 64 << 10
+
+// FingerprintFunc 根据请求计算指纹，body为FingerprintBody时预先读取的请求体，否则为nil
+type FingerprintFunc func(c *gin.Context, body []byte) string
+
+// requestFingerprint 在c.Next前计算指纹，FingerprintBody时读取的请求体会重新放回，handler仍能读到完整的请求体
+func requestFingerprint(c *gin.Context, conf GinLoggerConfig) string {
+	var body []byte
+	if conf.FingerprintBody && c.Request.Body != nil && c.Request.Body != http.NoBody {
+		max := conf.MaxFingerprintBody
+		if max <= 0 {
+			max = defaultMaxFingerprintBody
+		}
+		orig := c.Request.Body
+		body, _ = io.ReadAll(io.LimitReader(orig, int64(max)))
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), orig), orig}
+	}
+	if conf.Fingerprint != nil {
+		return conf.Fingerprint(c, body)
+	}
+	return DefaultFingerprint(c, conf.FingerprintHeaders, body)
+}
+
+// DefaultFingerprint method、路径(含查询参数)、headers中各请求头的值及body的SHA-256摘要，取前16位十六进制
+func DefaultFingerprint(c *gin.Context, headers []string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, c.Request.Method)
+	h.Write([]byte{'\n'})
+	io.WriteString(h, c.Request.URL.RequestURI())
+	for _, name := range headers {
+		h.Write([]byte{'\n'})
+		io.WriteString(h, http.CanonicalHeaderKey(name))
+		h.Write([]byte{':'})
+		io.WriteString(h, c.GetHeader(name))
+	}
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"sync"
+)
+
+/*
+	@func: 记录功能开关的判定结果
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const (
+	// flagSampleFirst、flagSampleThereafter 每秒每个开关先记录10条，之后每100条记录一条
+	flagSampleFirst      = 10
+	flagSampleThereafter = 100
+)
+
+var (
+	flagMu     sync.Mutex
+	flagBase   *zap.Logger
+	flagLogger *zap.Logger
+)
+
+// getFlagLogger 名为flag、单独采样的子logger，全局logger重新初始化后重新创建
+func getFlagLogger() *zap.Logger {
+	flagMu.Lock()
+	defer flagMu.Unlock()
+	if flagBase != logger {
+		flagBase = logger
+		flagLogger = SampledLogger("flag", flagSampleFirst, flagSampleThereafter).WithOptions(zap.AddCallerSkip(1))
+	}
+	return flagLogger
+}
+
+// FlagFields 功能开关日志的统一字段
+func FlagFields(flag, variant string) []zap.Field {
+	return []zap.Field{
+		zap.String("flag", flag),
+		zap.String("variant", variant),
+	}
+}
+
+// FlagLog 以Debug记录开关flag判定返回的variant，fields中可带上用户、租户等，
+// 按开关每秒先记录10条、之后每100条记录一条，避免高频开关刷屏
+func FlagLog(flag, variant string, fields ...zap.Field) {
+	l := getFlagLogger()
+	if ce := l.Check(zap.DebugLevel, "[flag] "+flag); ce != nil {
+		ce.Write(append(FlagFields(flag, variant), fields...)...)
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+)
+
+/*
+	@func: 保留最近的Debug日志，记录Error时一并输出
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// defaultRecorderEntries 默认保留的Debug日志条数
+const defaultRecorderEntries = 32
+
+// debugRing 保留最近size条未输出的Debug日志
+type debugRing struct {
+	mu      sync.Mutex
+	entries []bufferedEntry
+	next    int
+	full    bool
+}
+
+func (r *debugRing) add(e bufferedEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// drain 按时间顺序取出并清空保留的日志
+func (r *debugRing) drain() recentEntries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out recentEntries
+	if r.full {
+		out = append(out, r.entries[r.next:]...)
+	}
+	out = append(out, r.entries[:r.next]...)
+	for i := range r.entries {
+		r.entries[i] = bufferedEntry{}
+	}
+	r.next, r.full = 0, false
+	return out
+}
+
+// recentEntries 以数组输出的Debug日志，每条包含time、msg及调用时传入的字段
+type recentEntries []bufferedEntry
+
+func (entries recentEntries) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, e := range entries {
+		e := e
+		enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddTime("time", e.entry.Time)
+			enc.AddString("msg", e.entry.Message)
+			for _, f := range e.fields {
+				f.AddTo(enc)
+			}
+			return nil
+		}))
+	}
+	return nil
+}
+
+// recorderCore 未启用Debug时把Debug日志保留在debugRing中，记录Error及以上级别时以recent_debug字段附带输出
+type recorderCore struct {
+	zapcore.Core
+	ring *debugRing
+}
+
+func (c *recorderCore) Enabled(lvl zapcore.Level) bool {
+	return lvl == zapcore.DebugLevel || c.Core.Enabled(lvl)
+}
+
+func (c *recorderCore) With(fields []zapcore.Field) zapcore.Core {
+	return &recorderCore{Core: c.Core.With(fields), ring: c.ring}
+}
+
+func (c *recorderCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level == zapcore.DebugLevel && !c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	if ent.Level >= zapcore.ErrorLevel && c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *recorderCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level == zapcore.DebugLevel {
+		c.ring.add(bufferedEntry{entry: ent, fields: fields})
+		return nil
+	}
+	if recent := c.ring.drain(); len(recent) > 0 {
+		fields = append(fields[:len(fields):len(fields)], zap.Array("recent_debug", recent))
+	}
+	return writeChecked(c.Core, ent, fields)
+}
+
+// FlightRecorder 包装l，未启用Debug时保留最近size条Debug日志，
+// 通过返回的logger记录Error及以上级别时以recent_debug字段一并输出，<=0时为32条，
+// 适合每个请求或每个goroutine单独创建
+func FlightRecorder(l *zap.Logger, size int) *zap.Logger {
+	if size <= 0 {
+		size = defaultRecorderEntries
+	}
+	ring := &debugRing{entries: make([]bufferedEntry, size)}
+	return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &recorderCore{Core: core, ring: ring}
+	}))
+}
+
+// GinFlightRecorder 为每个请求安装FlightRecorder包装的logger，通过BufferedLogger获取
+func GinFlightRecorder(size int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(bufferedLoggerKey, FlightRecorder(logger, size))
+		c.Next()
+	}
+}
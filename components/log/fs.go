@@ -0,0 +1,86 @@
+package log
+
+import (
+	"io"
+	"os"
+)
+
+/*
+	@func: 文件操作的抽象，测试中可替换为内存实现
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// FileSystem 本包除rotatelogs外的文件操作，包括清理备份、校验和、检测软链接和自检
+type FileSystem interface {
+	MkdirAll(path string, perm os.FileMode) error
+	Open(name string) (io.ReadCloser, error)
+	OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	Remove(name string) error
+	Symlink(oldname, newname string) error
+}
+
+// RotatingWriter 按时间分割的日志文件writer，*rotatelogs.RotateLogs实现了该接口
+type RotatingWriter interface {
+	io.Writer
+	CurrentFileName() string
+	Rotate() error
+}
+
+// WriterFactory 创建后缀为suffix的日志文件writer，linkName非空时需维护指向当前文件的软链接
+type WriterFactory func(suffix string, rotate RotateConfig, linkName string) (RotatingWriter, error)
+
+var (
+	fsys          FileSystem    = osFS{}
+	writerFactory WriterFactory = getWriter
+)
+
+// SetFileSystemForTest 仅供测试使用，替换本包的文件操作，如NewMemFS，返回恢复的函数
+func SetFileSystemForTest(fs FileSystem) (restore func()) {
+	old := fsys
+	fsys = fs
+	return func() {
+		fsys = old
+	}
+}
+
+// SetWriterFactoryForTest 仅供测试使用，替换日志文件writer的创建方式，需在InitLogger前调用，返回恢复的函数
+func SetWriterFactoryForTest(factory WriterFactory) (restore func()) {
+	old := writerFactory
+	writerFactory = factory
+	return func() {
+		writerFactory = old
+	}
+}
+
+type osFS struct{}
+
+func (osFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (osFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFS) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
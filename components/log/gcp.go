@@ -0,0 +1,91 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"strconv"
+)
+
+/*
+	@func: Google Cloud Logging格式的JSON编码
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const (
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanIDKey         = "logging.googleapis.com/spanId"
+	gcpTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+// gcpEncoder 级别输出为severity，消息输出为message，调用位置输出为sourceLocation对象
+type gcpEncoder struct {
+	zapcore.Encoder
+}
+
+func newGCPEncoder(encoderConfig zapcore.EncoderConfig) zapcore.Encoder {
+	encoderConfig.LevelKey = "severity"
+	encoderConfig.EncodeLevel = gcpLevelEncoder
+	encoderConfig.MessageKey = "message"
+	encoderConfig.TimeKey = "time"
+	encoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	encoderConfig.CallerKey = ""
+	encoderConfig.FunctionKey = ""
+	return gcpEncoder{zapcore.NewJSONEncoder(encoderConfig)}
+}
+
+func (e gcpEncoder) Clone() zapcore.Encoder {
+	return gcpEncoder{e.Encoder.Clone()}
+}
+
+func (e gcpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if ent.Caller.Defined {
+		fields = append(fields[:len(fields):len(fields)], zap.Object(gcpSourceLocationKey, gcpSourceLocation(ent.Caller)))
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
+
+type gcpSourceLocation zapcore.EntryCaller
+
+func (s gcpSourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", s.File)
+	enc.AddString("line", strconv.Itoa(s.Line))
+	enc.AddString("function", s.Function)
+	return nil
+}
+
+// gcpLevelEncoder 按Cloud Logging的LogSeverity输出级别
+func gcpLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch l {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	case zapcore.DPanicLevel:
+		enc.AppendString("CRITICAL")
+	case zapcore.PanicLevel:
+		enc.AppendString("ALERT")
+	case zapcore.FatalLevel:
+		enc.AppendString("EMERGENCY")
+	default:
+		enc.AppendString("DEFAULT")
+	}
+}
+
+// GCPTraceFields Cloud Logging用于关联trace的字段，traceID为32位十六进制，spanID为空时不输出
+func GCPTraceFields(projectID, traceID, spanID string, sampled bool) []zap.Field {
+	fields := []zap.Field{
+		zap.String(gcpTraceKey, "projects/"+projectID+"/traces/"+traceID),
+		zap.Bool(gcpTraceSampledKey, sampled),
+	}
+	if spanID != "" {
+		fields = append(fields, zap.String(gcpSpanIDKey, spanID))
+	}
+	return fields
+}
@@ -0,0 +1,97 @@
+package grpclog
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"go_components_record/components/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+	"time"
+)
+
+/*
+	@func: grpc服务端的日志与recover拦截器，单独成包使不使用grpc的服务不必依赖grpc
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// UnaryServerInterceptor 记录一元调用的访问日志，字段与log.GinLogger保持一致
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := log.Now()
+		resp, err := handler(ctx, req)
+		log.WithoutCaller().Info(info.FullMethod, grpcFields(ctx, info.FullMethod, log.Now().Sub(start), err)...)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor 记录流式调用的访问日志，流结束时输出
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := log.Now()
+		err := handler(srv, ss)
+		log.WithoutCaller().Info(info.FullMethod, grpcFields(ss.Context(), info.FullMethod, log.Now().Sub(start), err)...)
+		return err
+	}
+}
+
+// UnaryServerRecovery recover掉一元调用中的panic，返回codes.Internal
+func UnaryServerRecovery(stack bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = grpcRecover(ctx, info.FullMethod, r, stack)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRecovery recover掉流式调用中的panic，返回codes.Internal
+func StreamServerRecovery(stack bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = grpcRecover(ss.Context(), info.FullMethod, r, stack)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func grpcFields(ctx context.Context, method string, cost time.Duration, err error) []zap.Field {
+	errors := ""
+	if err != nil {
+		errors = err.Error()
+	}
+	return []zap.Field{
+		zap.String("grpc_code", status.Code(err).String()),
+		zap.String("method", method),
+		zap.String("ip", peerAddr(ctx)),
+		zap.String("errors", errors),
+		zap.Duration("cost", cost),
+	}
+}
+
+// grpcRecover 记录panic并转换为codes.Internal错误
+func grpcRecover(ctx context.Context, method string, r interface{}, stack bool) error {
+	fields := append(log.PanicFields(r),
+		zap.String("method", method),
+		zap.String("ip", peerAddr(ctx)),
+	)
+	if stack {
+		fields = append(fields, zap.String("stack", string(debug.Stack())))
+	}
+	log.GetErrorLogInstance().Error("[Recovery from panic]", fields...)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package grpclog
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap/zapcore"
+	"go_components_record/components/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestUnaryServerInterceptorLogsCode(t *testing.T) {
+	logs, restore := log.ObserveForTest(zapcore.InfoLevel)
+	defer restore()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc.Echo/Say"}
+	_, err := UnaryServerInterceptor()(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("err = %v", err)
+	}
+	entry, ok := logs.FindEntry("/svc.Echo/Say")
+	if !ok {
+		t.Fatal("no access log")
+	}
+	log.AssertFieldEquals(t, entry, "grpc_code", "NotFound")
+	log.AssertFieldEquals(t, entry, "method", "/svc.Echo/Say")
+}
+
+func TestUnaryServerRecoveryReturnsInternal(t *testing.T) {
+	logs, restore := log.ObserveForTest(zapcore.InfoLevel)
+	defer restore()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc.Echo/Say"}
+	_, err := UnaryServerRecovery(false)(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic(errors.New("boom"))
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("err = %v, want codes.Internal", err)
+	}
+	entry, ok := logs.FindEntry("[Recovery from panic]")
+	if !ok {
+		t.Fatal("no panic log")
+	}
+	log.AssertFieldEquals(t, entry, "panic_error", "boom")
+	log.AssertFieldEquals(t, entry, "method", "/svc.Echo/Say")
+}
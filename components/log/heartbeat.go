@@ -0,0 +1,37 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"runtime"
+	"time"
+)
+
+/*
+	@func: 定时输出心跳日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// startTime 进程启动时间，用于计算uptime
+var startTime = time.Now()
+
+// defaultHeartbeatInterval 默认的心跳间隔
+const defaultHeartbeatInterval = time.Minute
+
+// StartHeartbeat 每隔interval以Info输出一条heartbeat日志，附带uptime、goroutine数和丢弃计数，
+// interval<=0时为1分钟，返回的stop函数会等待后台goroutine退出，可重复调用
+func StartHeartbeat(interval time.Duration, fields ...zap.Field) (stop func()) {
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	return startTicker(interval, func() {
+		s := Stats()
+		WithoutCaller().Info("heartbeat", append([]zap.Field{
+			zap.Duration("uptime", now().Sub(startTime)),
+			zap.Int("goroutines", runtime.NumGoroutine()),
+			zap.Uint64("dropped_by_sampling", s.DroppedBySampling),
+			zap.Uint64("dropped_by_buffer_overflow", s.DroppedByBufferOverflow),
+			zap.Uint64("dropped_by_sink", s.DroppedBySink),
+		}, fields...)...)
+	})
+}
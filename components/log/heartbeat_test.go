@@ -0,0 +1,14 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartHeartbeatDefaultsNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		stop := StartHeartbeat(interval)
+		stop()
+		stop()
+	}
+}
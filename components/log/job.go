@@ -0,0 +1,58 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+	@func: 记录定时任务的开始、成功和失败
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// JobLog 一次定时任务的执行，日志都带有job和run_id字段
+type JobLog struct {
+	name   string
+	runID  string
+	start  time.Time
+	logger *zap.Logger
+	errLog *zap.Logger
+}
+
+// JobLogger 为名为name的任务的一次执行生成run_id，每次执行任务时创建
+func JobLogger(name string) *JobLog {
+	job := &JobLog{name: name, runID: randomID(), start: now()}
+	fields := []zap.Field{zap.String("job", name), zap.String("run_id", job.runID)}
+	job.logger = logger.With(fields...)
+	job.errLog = errLogger.With(fields...)
+	return job
+}
+
+// RunID 本次执行的ID
+func (j *JobLog) RunID() string {
+	return j.runID
+}
+
+// Logger 带job和run_id字段的logger，用于记录任务执行过程中的日志
+func (j *JobLog) Logger() *zap.Logger {
+	return j.logger
+}
+
+// Start 记录任务开始，并从此时开始计算耗时
+func (j *JobLog) Start(fields ...zap.Field) {
+	j.start = now()
+	j.logger.WithOptions(zap.AddCallerSkip(1)).Info("[job] start "+j.name, fields...)
+}
+
+// Success 记录任务成功及耗时
+func (j *JobLog) Success(fields ...zap.Field) {
+	j.logger.WithOptions(zap.AddCallerSkip(1)).Info("[job] success "+j.name,
+		append(fields, zap.Duration("cost", now().Sub(j.start)))...)
+}
+
+// Failure 以Error将任务失败及耗时写入错误日志
+func (j *JobLog) Failure(err error, fields ...zap.Field) {
+	j.errLog.WithOptions(zap.AddCallerSkip(1)).Error("[job] failure "+j.name,
+		append(fields, zap.Duration("cost", now().Sub(j.start)), zap.Error(err))...)
+}
@@ -0,0 +1,125 @@
+package journald
+
+import (
+	"errors"
+	"fmt"
+	"github.com/coreos/go-systemd/v22/journal"
+	"go.uber.org/zap/zapcore"
+	"strconv"
+	"strings"
+)
+
+/*
+	@func: 输出到systemd journal的core，单独成包使不使用journald的服务不必依赖go-systemd
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// ErrUnavailable 当前不在systemd下运行，journal不可用
+var ErrUnavailable = errors.New("log: journald is not available")
+
+// journaldCore 将日志以结构化字段写入journal，zap字段名转为journal要求的大写字段名
+type journaldCore struct {
+	zapcore.LevelEnabler
+	fields map[string]string
+}
+
+// NewCore 创建输出到journal的core，通过LoggerConfig.Cores加入logger，当前不在systemd下运行时返回ErrUnavailable
+func NewCore(enab zapcore.LevelEnabler) (zapcore.Core, error) {
+	if !journal.Enabled() {
+		return nil, ErrUnavailable
+	}
+	return &journaldCore{LevelEnabler: enab, fields: map[string]string{}}, nil
+}
+
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &journaldCore{LevelEnabler: c.LevelEnabler, fields: make(map[string]string, len(c.fields)+len(fields))}
+	for k, v := range c.fields {
+		clone.fields[k] = v
+	}
+	addJournalFields(clone.fields, fields)
+	return clone
+}
+
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	vars := make(map[string]string, len(c.fields)+len(fields)+4)
+	for k, v := range c.fields {
+		vars[k] = v
+	}
+	addJournalFields(vars, fields)
+	if ent.LoggerName != "" {
+		vars["LOGGER"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		vars["CODE_FILE"] = ent.Caller.File
+		vars["CODE_LINE"] = strconv.Itoa(ent.Caller.Line)
+		vars["CODE_FUNC"] = ent.Caller.Function
+	}
+	if ent.Stack != "" {
+		vars["STACK"] = ent.Stack
+	}
+	return journal.Send(ent.Message, journalPriority(ent.Level), vars)
+}
+
+func (c *journaldCore) Sync() error {
+	return nil
+}
+
+// addJournalFields 将zap字段编码后写入vars
+func addJournalFields(vars map[string]string, fields []zapcore.Field) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	for k, v := range enc.Fields {
+		vars[journalFieldName(k)] = fmt.Sprint(v)
+	}
+}
+
+// journalFieldName journal字段名只能包含大写字母、数字和下划线，且不能以下划线开头
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "F_" + name
+	}
+	return name
+}
+
+// journalPriority zap级别对应的syslog优先级
+func journalPriority(level zapcore.Level) journal.Priority {
+	switch level {
+	case zapcore.DebugLevel:
+		return journal.PriDebug
+	case zapcore.InfoLevel:
+		return journal.PriInfo
+	case zapcore.WarnLevel:
+		return journal.PriWarning
+	case zapcore.ErrorLevel:
+		return journal.PriErr
+	case zapcore.DPanicLevel:
+		return journal.PriCrit
+	case zapcore.PanicLevel:
+		return journal.PriAlert
+	case zapcore.FatalLevel:
+		return journal.PriEmerg
+	default:
+		return journal.PriNotice
+	}
+}
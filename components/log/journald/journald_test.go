@@ -0,0 +1,21 @@
+package journald
+
+import (
+	"testing"
+)
+
+func TestJournalFieldName(t *testing.T) {
+	cases := map[string]string{
+		"request_id": "REQUEST_ID",
+		"http.path":  "HTTP_PATH",
+		"_hidden":    "HIDDEN",
+		"2xx":        "F_2XX",
+		"___":        "F_",
+		"ApiKey":     "APIKEY",
+	}
+	for key, want := range cases {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	stdlog "log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	@func: 按logger名称覆盖日志级别
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+var (
+	namedLevelsMu sync.Mutex
+	// namedLevels 写时复制，读取时无需加锁
+	namedLevels atomic.Pointer[map[string]zapcore.Level]
+)
+
+// SetLevelFor 为名为name的logger(及其子logger)设置最低级别，如将"noisylib"提高到Warn
+func SetLevelFor(name string, level zapcore.Level) {
+	namedLevelsMu.Lock()
+	defer namedLevelsMu.Unlock()
+	levels := map[string]zapcore.Level{}
+	if old := namedLevels.Load(); old != nil {
+		for k, v := range *old {
+			levels[k] = v
+		}
+	}
+	levels[name] = level
+	namedLevels.Store(&levels)
+}
+
+// ResetLevelFor 取消名为name的logger的级别覆盖
+func ResetLevelFor(name string) {
+	namedLevelsMu.Lock()
+	defer namedLevelsMu.Unlock()
+	old := namedLevels.Load()
+	if old == nil {
+		return
+	}
+	levels := map[string]zapcore.Level{}
+	for k, v := range *old {
+		if k != name {
+			levels[k] = v
+		}
+	}
+	namedLevels.Store(&levels)
+}
+
+// StdLogger 返回写入名为name的子logger的标准库logger，供只接受*log.Logger的第三方库使用
+func StdLogger(name string) *stdlog.Logger {
+	return zap.NewStdLog(logger.Named(name))
+}
+
+var (
+	stdLogMu      sync.Mutex
+	stdLogRestore func()
+)
+
+// RedirectStdLog 将标准库log包的全局logger(log.Print等)以Info写入名为stdlog的子logger，
+// 返回恢复原输出的函数，也可调用RestoreStdLog恢复，重复调用时先恢复上一次的重定向
+func RedirectStdLog() (restore func()) {
+	stdLogMu.Lock()
+	defer stdLogMu.Unlock()
+	if stdLogRestore != nil {
+		stdLogRestore()
+	}
+	undo := zap.RedirectStdLog(logger.Named("stdlog"))
+	var once sync.Once
+	restore = func() {
+		once.Do(undo)
+	}
+	stdLogRestore = restore
+	return restore
+}
+
+// RestoreStdLog 恢复RedirectStdLog之前标准库log包的输出，未重定向时不做任何操作
+func RestoreStdLog() {
+	stdLogMu.Lock()
+	defer stdLogMu.Unlock()
+	if stdLogRestore != nil {
+		stdLogRestore()
+		stdLogRestore = nil
+	}
+}
+
+// SampledLogger 返回名为name、单独采样的子logger，每秒每条相同消息先记录first条，之后每thereafter条记录一条，
+// 不影响全局logger的采样设置
+func SampledLogger(name string, first, thereafter int) *zap.Logger {
+	return logger.Named(name).WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newSamplerCore(core, first, thereafter)
+	}))
+}
+
+// levelForName 按名称查找覆盖的级别，找不到时逐级向上查找父logger
+func levelForName(name string) (zapcore.Level, bool) {
+	levels := namedLevels.Load()
+	if levels == nil || len(*levels) == 0 {
+		return 0, false
+	}
+	for name != "" {
+		if level, ok := (*levels)[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return 0, false
+}
+
+// namedLevelCore 在原有级别之上，按logger名称过滤低于覆盖级别的日志
+type namedLevelCore struct {
+	zapcore.Core
+}
+
+func (c namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return namedLevelCore{c.Core.With(fields)}
+}
+
+func (c namedLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if level, ok := levelForName(ent.LoggerName); ok && ent.Level < level {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// contextLevelCore 按ContextWithLevel设置的级别过滤，代替全局级别和按名称覆盖的级别
+type contextLevelCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c contextLevelCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.level
+}
+
+func (c contextLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return contextLevelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c contextLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 带上forceWriteField，使低于全局级别的日志也能写入各个输出端
+func (c contextLevelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, append(fields[:len(fields):len(fields)], forceWriteField))
+}
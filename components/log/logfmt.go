@@ -0,0 +1,287 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+	@func: logfmt编码器，输出key=value格式
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder logfmt格式的zapcore.Encoder，嵌套对象展开为a.b=c，数组以JSON作为值
+type logfmtEncoder struct {
+	cfg    *zapcore.EncoderConfig
+	buf    *buffer.Buffer
+	prefix string
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{cfg: &cfg, buf: logfmtPool.Get()}
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{cfg: enc.cfg, buf: logfmtPool.Get(), prefix: enc.prefix}
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{cfg: enc.cfg, buf: logfmtPool.Get()}
+	cfg := enc.cfg
+	if cfg.TimeKey != "" {
+		final.AddTime(cfg.TimeKey, ent.Time)
+	}
+	if cfg.LevelKey != "" {
+		if cfg.EncodeLevel != nil {
+			final.addPrimitive(cfg.LevelKey, func(pae zapcore.PrimitiveArrayEncoder) { cfg.EncodeLevel(ent.Level, pae) })
+		} else {
+			final.AddString(cfg.LevelKey, ent.Level.String())
+		}
+	}
+	if cfg.NameKey != "" && ent.LoggerName != "" {
+		final.AddString(cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		if cfg.CallerKey != "" {
+			if cfg.EncodeCaller != nil {
+				final.addPrimitive(cfg.CallerKey, func(pae zapcore.PrimitiveArrayEncoder) { cfg.EncodeCaller(ent.Caller, pae) })
+			} else {
+				final.AddString(cfg.CallerKey, ent.Caller.TrimmedPath())
+			}
+		}
+		if cfg.FunctionKey != "" {
+			final.AddString(cfg.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if cfg.MessageKey != "" {
+		final.AddString(cfg.MessageKey, ent.Message)
+	}
+	if enc.buf.Len() > 0 {
+		final.separate()
+		final.buf.Write(enc.buf.Bytes())
+	}
+	final.prefix = enc.prefix
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.prefix = ""
+	if ent.Stack != "" && cfg.StacktraceKey != "" {
+		final.AddString(cfg.StacktraceKey, ent.Stack)
+	}
+	if cfg.LineEnding != "" {
+		final.buf.AppendString(cfg.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+func (enc *logfmtEncoder) separate() {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+}
+
+// addKey 写入key=，key中的空白、等号和引号替换为下划线
+func (enc *logfmtEncoder) addKey(key string) {
+	enc.separate()
+	enc.buf.AppendString(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, enc.prefix+key))
+	enc.buf.AppendByte('=')
+}
+
+// appendValue 值为空或包含空白、等号、引号及不可打印字符时加引号
+func (enc *logfmtEncoder) appendValue(value string) {
+	if needsQuote(value) {
+		enc.buf.AppendString(strconv.Quote(value))
+	} else {
+		enc.buf.AppendString(value)
+	}
+}
+
+func needsQuote(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (enc *logfmtEncoder) addRaw(key, value string) {
+	enc.addKey(key)
+	enc.buf.AppendString(value)
+}
+
+// addPrimitive 通过EncodeTime等编码函数得到值后写入
+func (enc *logfmtEncoder) addPrimitive(key string, encode func(zapcore.PrimitiveArrayEncoder)) {
+	values := &primitiveValues{}
+	encode(values)
+	enc.addKey(key)
+	enc.appendValue(strings.Join(values.values, ","))
+}
+
+func (enc *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := m.AddArray(key, arr); err != nil {
+		return err
+	}
+	return enc.AddReflected(key, m.Fields[key])
+}
+
+func (enc *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	prefix := enc.prefix
+	enc.prefix = prefix + key + "."
+	err := obj.MarshalLogObject(enc)
+	enc.prefix = prefix
+	return err
+}
+
+func (enc *logfmtEncoder) AddBinary(key string, value []byte) {
+	enc.AddString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (enc *logfmtEncoder) AddByteString(key string, value []byte) {
+	enc.AddString(key, string(value))
+}
+
+func (enc *logfmtEncoder) AddBool(key string, value bool) {
+	enc.addRaw(key, strconv.FormatBool(value))
+}
+
+func (enc *logfmtEncoder) AddComplex128(key string, value complex128) {
+	enc.addKey(key)
+	enc.appendValue(strconv.FormatComplex(value, 'f', -1, 128))
+}
+
+func (enc *logfmtEncoder) AddComplex64(key string, value complex64) {
+	enc.addKey(key)
+	enc.appendValue(strconv.FormatComplex(complex128(value), 'f', -1, 64))
+}
+
+func (enc *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	if enc.cfg.EncodeDuration == nil {
+		enc.AddInt64(key, int64(value))
+		return
+	}
+	enc.addPrimitive(key, func(pae zapcore.PrimitiveArrayEncoder) { enc.cfg.EncodeDuration(value, pae) })
+}
+
+func (enc *logfmtEncoder) AddFloat64(key string, value float64) {
+	enc.addRaw(key, formatFloat(value, 64))
+}
+
+func (enc *logfmtEncoder) AddFloat32(key string, value float32) {
+	enc.addRaw(key, formatFloat(float64(value), 32))
+}
+
+func (enc *logfmtEncoder) AddInt(key string, value int)     { enc.AddInt64(key, int64(value)) }
+func (enc *logfmtEncoder) AddInt32(key string, value int32) { enc.AddInt64(key, int64(value)) }
+func (enc *logfmtEncoder) AddInt16(key string, value int16) { enc.AddInt64(key, int64(value)) }
+func (enc *logfmtEncoder) AddInt8(key string, value int8)   { enc.AddInt64(key, int64(value)) }
+
+func (enc *logfmtEncoder) AddInt64(key string, value int64) {
+	enc.addRaw(key, strconv.FormatInt(value, 10))
+}
+
+func (enc *logfmtEncoder) AddString(key, value string) {
+	enc.addKey(key)
+	enc.appendValue(value)
+}
+
+func (enc *logfmtEncoder) AddTime(key string, value time.Time) {
+	if enc.cfg.EncodeTime == nil {
+		enc.AddInt64(key, value.UnixNano())
+		return
+	}
+	enc.addPrimitive(key, func(pae zapcore.PrimitiveArrayEncoder) { enc.cfg.EncodeTime(value, pae) })
+}
+
+func (enc *logfmtEncoder) AddUint(key string, value uint)       { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUint32(key string, value uint32)   { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUint16(key string, value uint16)   { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUint8(key string, value uint8)     { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUintptr(key string, value uintptr) { enc.AddUint64(key, uint64(value)) }
+
+func (enc *logfmtEncoder) AddUint64(key string, value uint64) {
+	enc.addRaw(key, strconv.FormatUint(value, 10))
+}
+
+// AddReflected 字符串直接输出，其余值以JSON作为值
+func (enc *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	if s, ok := value.(string); ok {
+		enc.AddString(key, s)
+		return nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	enc.AddString(key, string(b))
+	return nil
+}
+
+func (enc *logfmtEncoder) OpenNamespace(key string) {
+	enc.prefix = enc.prefix + key + "."
+}
+
+func formatFloat(value float64, bitSize int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'f', -1, bitSize)
+}
+
+// primitiveValues 收集EncodeTime、EncodeLevel等编码函数输出的值
+type primitiveValues struct {
+	values []string
+}
+
+func (p *primitiveValues) AppendBool(v bool)         { p.append(strconv.FormatBool(v)) }
+func (p *primitiveValues) AppendByteString(v []byte) { p.append(string(v)) }
+func (p *primitiveValues) AppendComplex128(v complex128) {
+	p.append(strconv.FormatComplex(v, 'f', -1, 128))
+}
+func (p *primitiveValues) AppendComplex64(v complex64) {
+	p.append(strconv.FormatComplex(complex128(v), 'f', -1, 64))
+}
+func (p *primitiveValues) AppendFloat64(v float64) { p.append(formatFloat(v, 64)) }
+func (p *primitiveValues) AppendFloat32(v float32) { p.append(formatFloat(float64(v), 32)) }
+func (p *primitiveValues) AppendInt(v int)         { p.AppendInt64(int64(v)) }
+func (p *primitiveValues) AppendInt64(v int64)     { p.append(strconv.FormatInt(v, 10)) }
+func (p *primitiveValues) AppendInt32(v int32)     { p.AppendInt64(int64(v)) }
+func (p *primitiveValues) AppendInt16(v int16)     { p.AppendInt64(int64(v)) }
+func (p *primitiveValues) AppendInt8(v int8)       { p.AppendInt64(int64(v)) }
+func (p *primitiveValues) AppendString(v string)   { p.append(v) }
+func (p *primitiveValues) AppendUint(v uint)       { p.AppendUint64(uint64(v)) }
+func (p *primitiveValues) AppendUint64(v uint64)   { p.append(strconv.FormatUint(v, 10)) }
+func (p *primitiveValues) AppendUint32(v uint32)   { p.AppendUint64(uint64(v)) }
+func (p *primitiveValues) AppendUint16(v uint16)   { p.AppendUint64(uint64(v)) }
+func (p *primitiveValues) AppendUint8(v uint8)     { p.AppendUint64(uint64(v)) }
+func (p *primitiveValues) AppendUintptr(v uintptr) { p.AppendUint64(uint64(v)) }
+func (p *primitiveValues) append(v string)         { p.values = append(p.values, v) }
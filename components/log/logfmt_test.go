@@ -0,0 +1,75 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"testing"
+	"time"
+)
+
+// encodeLogfmt 只保留消息和字段，不输出时间、级别和调用位置
+func encodeLogfmt(t *testing.T, fields ...zap.Field) string {
+	t.Helper()
+	enc := newLogfmtEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "m"}, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestLogfmtEscaping(t *testing.T) {
+	cases := []struct {
+		name  string
+		field zap.Field
+		want  string
+	}{
+		{"plain", zap.String("k", "v"), `msg=m k=v` + "\n"},
+		{"empty value", zap.String("k", ""), `msg=m k=""` + "\n"},
+		{"space", zap.String("k", "a b"), `msg=m k="a b"` + "\n"},
+		{"quote", zap.String("k", `say "hi"`), `msg=m k="say \"hi\""` + "\n"},
+		{"equals", zap.String("k", "a=b"), `msg=m k="a=b"` + "\n"},
+		{"newline", zap.String("k", "line1\nline2"), `msg=m k="line1\nline2"` + "\n"},
+		{"tab", zap.String("k", "a\tb"), `msg=m k="a\tb"` + "\n"},
+		{"backslash", zap.String("k", `C:\tmp`), `msg=m k="C:\\tmp"` + "\n"},
+		{"control", zap.String("k", "a\x00b"), `msg=m k="a\x00b"` + "\n"},
+		{"unicode", zap.String("k", "日志"), `msg=m k=日志` + "\n"},
+		{"invalid utf8", zap.String("k", "a\xffb"), `msg=m k="a\xffb"` + "\n"},
+		{"key with space", zap.String("a key", "v"), `msg=m a_key=v` + "\n"},
+		{"key with equals", zap.String("a=b", "v"), `msg=m a_b=v` + "\n"},
+		{"key with quote", zap.String(`a"b`, "v"), `msg=m a_b=v` + "\n"},
+		{"bool", zap.Bool("ok", true), `msg=m ok=true` + "\n"},
+		{"int", zap.Int("n", -3), `msg=m n=-3` + "\n"},
+		{"nan", zap.Float64("f", math.NaN()), `msg=m f=NaN` + "\n"},
+		{"duration", zap.Duration("d", time.Second), `msg=m d=1000000000` + "\n"},
+		{"array as json", zap.Strings("s", []string{"a", "b c"}), `msg=m s="[\"a\",\"b c\"]"` + "\n"},
+		{"nested object", zap.Object("req", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("path", "/a b")
+			enc.AddInt("status", 200)
+			return nil
+		})), `msg=m req.path="/a b" req.status=200` + "\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := encodeLogfmt(t, tc.field); got != tc.want {
+				t.Errorf("got  %q\nwant %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLogfmtMessageAndContext(t *testing.T) {
+	enc := newLogfmtEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	zap.String("svc", "api").AddTo(enc)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "request failed: x=1"}, []zap.Field{zap.Namespace("ctx"), zap.String("id", "7")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+	want := `msg="request failed: x=1" svc=api ctx.id=7` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
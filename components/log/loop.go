@@ -0,0 +1,48 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+	@func: 后台循环的启动和停止
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// startLoop 在后台goroutine中对ch收到的每个值调用fn，
+// 返回的stop函数会等待goroutine退出，可重复调用
+func startLoop[T any](ch <-chan T, fn func(T)) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case v := <-ch:
+				fn(v)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// startTicker 每隔interval在后台goroutine中调用一次fn，stop同startLoop
+func startTicker(interval time.Duration, fn func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	stopLoop := startLoop(ticker.C, func(time.Time) { fn() })
+	return func() {
+		stopLoop()
+		ticker.Stop()
+	}
+}
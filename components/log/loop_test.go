@@ -0,0 +1,24 @@
+package log
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartTickerStopWaitsForLoop(t *testing.T) {
+	var calls atomic.Int64
+	stop := startTicker(time.Millisecond, func() { calls.Add(1) })
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	n := calls.Load()
+	if n == 0 {
+		t.Fatal("fn never called")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := calls.Load(); got != n {
+		t.Fatalf("fn called %d times after stop", got-n)
+	}
+	// 重复调用不阻塞
+	stop()
+}
@@ -0,0 +1,231 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+	@func: 测试用的内存文件系统
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// MemFS 内存中的FileSystem，文件的修改时间取自包内时钟，可测试按MaxBackups清理、校验文件清理和自检，
+// 日志文件本身及MaxAge的按时间清理由rotatelogs直接读写磁盘，不经过FileSystem
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+	dirs  map[string]bool
+	// NoSymlink 为true时Symlink返回错误，用于模拟不支持软链接的文件系统
+	NoSymlink bool
+}
+
+type memFile struct {
+	data    bytes.Buffer
+	modTime time.Time
+	link    string
+}
+
+// NewMemFS 创建空的内存文件系统
+func NewMemFS() *MemFS {
+	return &MemFS{files: map[string]*memFile{}, dirs: map[string]bool{".": true}}
+}
+
+func (m *MemFS) MkdirAll(p string, _ os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for p = cleanPath(p); p != "." && p != "/"; p = path.Dir(p) {
+		m.dirs[p] = true
+	}
+	return nil
+}
+
+func (m *MemFS) Open(name string) (io.ReadCloser, error) {
+	data, err := m.ReadFile(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MemFS) OpenFile(name string, flag int, _ os.FileMode) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = cleanPath(name)
+	f, ok := m.files[name]
+	switch {
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !m.dirs[path.Dir(name)]:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		f = &memFile{modTime: now()}
+		m.files[name] = f
+	case flag&os.O_TRUNC != 0:
+		f.data.Reset()
+	}
+	return &memHandle{fs: m, f: f}, nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = cleanPath(name)
+	if f, ok := m.files[name]; ok {
+		return memEntry{name: path.Base(name), size: int64(f.data.Len()), modTime: f.modTime, link: f.link != ""}, nil
+	}
+	if m.dirs[name] {
+		return memEntry{name: path.Base(name), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *MemFS) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = cleanPath(name)
+	if !m.dirs[name] {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	var entries []os.DirEntry
+	for p, f := range m.files {
+		if path.Dir(p) == name {
+			entries = append(entries, memEntry{name: path.Base(p), size: int64(f.data.Len()), modTime: f.modTime, link: f.link != ""})
+		}
+	}
+	for p := range m.dirs {
+		if p != name && path.Dir(p) == name {
+			entries = append(entries, memEntry{name: path.Base(p), dir: true})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = cleanPath(name)
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	if !m.dirs[name] {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	// 与os.Remove相同，只能删除空目录
+	for p := range m.files {
+		if path.Dir(p) == name {
+			return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	for p := range m.dirs {
+		if p != name && path.Dir(p) == name {
+			return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	delete(m.dirs, name)
+	return nil
+}
+
+func (m *MemFS) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	newname = cleanPath(newname)
+	if m.NoSymlink {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrPermission}
+	}
+	if _, ok := m.files[newname]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	m.files[newname] = &memFile{modTime: now(), link: oldname}
+	return nil
+}
+
+// ReadFile 读取文件内容，用于测试断言
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[cleanPath(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), f.data.Bytes()...), nil
+}
+
+// SetModTime 设置文件的修改时间，用于构造过期文件
+func (m *MemFS) SetModTime(name string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[cleanPath(name)]
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	f.modTime = t
+	return nil
+}
+
+func cleanPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// memHandle OpenFile返回的句柄
+type memHandle struct {
+	fs *MemFS
+	f  *memFile
+}
+
+func (h *memHandle) Write(p []byte) (int, error) {
+	h.fs.mu.Lock()
+	defer h.fs.mu.Unlock()
+	h.f.modTime = now()
+	return h.f.data.Write(p)
+}
+
+func (h *memHandle) Close() error {
+	return nil
+}
+
+// memEntry 同时实现os.DirEntry和os.FileInfo
+type memEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	link    bool
+}
+
+func (e memEntry) Name() string               { return e.name }
+func (e memEntry) IsDir() bool                { return e.dir }
+func (e memEntry) Info() (os.FileInfo, error) { return e, nil }
+func (e memEntry) Size() int64                { return e.size }
+func (e memEntry) ModTime() time.Time         { return e.modTime }
+func (e memEntry) Sys() interface{}           { return nil }
+
+func (e memEntry) Type() os.FileMode {
+	return e.Mode().Type()
+}
+
+func (e memEntry) Mode() os.FileMode {
+	switch {
+	case e.dir:
+		return fs.ModeDir | 0755
+	case e.link:
+		return fs.ModeSymlink | 0777
+	default:
+		return 0644
+	}
+}
+
+var _ FileSystem = (*MemFS)(nil)
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// fixedClock 测试用的可调时钟
+type fixedClock struct {
+	t time.Time
+}
+
+func (c *fixedClock) Now() time.Time                         { return c.t }
+func (c *fixedClock) NewTicker(d time.Duration) *time.Ticker { return time.NewTicker(d) }
+
+// createMemFile 在clock的当前时间创建文件
+func createMemFile(t *testing.T, m *MemFS, name string) {
+	t.Helper()
+	w, err := m.OpenFile(name, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, name)
+	w.Close()
+}
+
+func TestPruneBackupsOnMemFS(t *testing.T) {
+	m := NewMemFS()
+	defer SetFileSystemForTest(m)()
+	clock := &fixedClock{t: time.Date(2023, 10, 9, 10, 0, 0, 0, time.UTC)}
+	defer SetClockForTest(clock)()
+
+	files := []string{
+		"log/error/2023/10/08/zap-20231008-2300-error.log",
+		"log/error/2023/10/09/zap-20231009-0900-error.log",
+		"log/error/2023/10/09/zap-20231009-1000-error.log",
+		"log/error/2023/10/09/zap-20231009-1100-error.log",
+	}
+	for _, f := range files {
+		m.MkdirAll(path.Dir(f), 0755)
+		createMemFile(t, m, f)
+		clock.t = clock.t.Add(time.Hour)
+	}
+	// 其他流的文件不受影响
+	m.MkdirAll("log", 0755)
+	createMemFile(t, m, "log/zap-20231008-2200.log")
+
+	pruneBackups("-error.log", files[3], 2)
+
+	for i, f := range files {
+		_, err := m.ReadFile(f)
+		if removed := errors.Is(err, os.ErrNotExist); removed != (i == 0) {
+			t.Errorf("%s removed = %v, want %v", f, removed, i == 0)
+		}
+	}
+	if _, err := m.ReadDir("log/error/2023/10/08"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty date dir not removed: %v", err)
+	}
+	if _, err := m.ReadDir("log/error/2023/10"); err != nil {
+		t.Errorf("non-empty month dir removed: %v", err)
+	}
+	if _, err := m.ReadFile("log/zap-20231008-2200.log"); err != nil {
+		t.Errorf("main stream file removed: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"mime"
+	"mime/multipart"
+)
+
+/*
+	@func: 记录multipart上传的文件信息
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// defaultMultipartFiles 默认最多记录的文件数
+const defaultMultipartFiles = 20
+
+// multipartFile 上传文件的元信息，不包含内容
+type multipartFile struct {
+	field       string
+	filename    string
+	contentType string
+	size        int64
+}
+
+type multipartFiles []multipartFile
+
+func (files multipartFiles) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, f := range files {
+		enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("field", f.field)
+			enc.AddString("filename", f.filename)
+			enc.AddString("content_type", f.contentType)
+			enc.AddInt64("size", f.size)
+			return nil
+		}))
+	}
+	return nil
+}
+
+// multipartTap handler读取请求体时把读到的内容同时交给后台goroutine解析，
+// 文件内容只计数丢弃，不占用内存，也不影响handler读取
+type multipartTap struct {
+	io.ReadCloser
+	pw    *io.PipeWriter
+	done  chan struct{}
+	max   int
+	files multipartFiles
+	total int
+	err   error
+}
+
+// tapMultipart 请求为multipart/form-data时包装请求体，否则返回nil
+func tapMultipart(c *gin.Context, max int) *multipartTap {
+	mediaType, params, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" || c.Request.Body == nil {
+		return nil
+	}
+	if max <= 0 {
+		max = defaultMultipartFiles
+	}
+	pr, pw := io.Pipe()
+	t := &multipartTap{ReadCloser: c.Request.Body, pw: pw, done: make(chan struct{}), max: max}
+	go t.parse(pr, params["boundary"])
+	c.Request.Body = t
+	return t
+}
+
+func (t *multipartTap) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.pw.Write(p[:n])
+	}
+	return n, err
+}
+
+func (t *multipartTap) parse(pr *io.PipeReader, boundary string) {
+	defer close(t.done)
+	// 解析失败后继续读完，避免阻塞handler读取请求体
+	defer io.Copy(io.Discard, pr)
+	mr := multipart.NewReader(pr, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.err = err
+			return
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		size, err := io.Copy(io.Discard, part)
+		if err != nil {
+			t.err = err
+			return
+		}
+		t.total++
+		if len(t.files) < t.max {
+			t.files = append(t.files, multipartFile{
+				field:       part.FormName(),
+				filename:    part.FileName(),
+				contentType: part.Header.Get("Content-Type"),
+				size:        size,
+			})
+		}
+	}
+}
+
+// close 结束解析并等待后台goroutine退出，可重复调用
+func (t *multipartTap) close() {
+	t.pw.Close()
+	<-t.done
+}
+
+// fields 结束解析并返回要记录的字段，handler未读完请求体或解析失败时返回nil
+func (t *multipartTap) fields() []zap.Field {
+	t.close()
+	if t.err != nil || t.total == 0 {
+		return nil
+	}
+	fields := []zap.Field{zap.Array("multipart_files", t.files)}
+	if t.total > len(t.files) {
+		fields = append(fields, zap.Int("multipart_files_total", t.total))
+	}
+	return fields
+}
@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestMultipartTapStoppedOnPanic(t *testing.T) {
+	if err := InitLoggerWithConfig(LoggerConfig{DisableStdout: true}); err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	fw.Write(bytes.Repeat([]byte("x"), 1<<10))
+	mw.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ interface{}) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	r.Use(GinLoggerWithConfig(GinLoggerConfig{LogMultipart: true}))
+	r.POST("/upload", func(c *gin.Context) {
+		// 只读一部分请求体，解析goroutine仍在等待后续内容
+		c.Request.Body.Read(make([]byte, 16))
+		panic("boom")
+	})
+
+	before := runtime.NumGoroutine()
+	const requests = 20
+	for i := 0; i < requests; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() >= before+requests/2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines grew from %d to %d after %d panicking uploads", before, runtime.NumGoroutine(), requests)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
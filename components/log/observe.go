@@ -0,0 +1,144 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"reflect"
+	"time"
+)
+
+/*
+	@func: 测试中捕获日志并断言字段
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// TestingT testing.T中断言用到的方法，避免本包依赖testing
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// ObservedLogs 捕获到的日志
+type ObservedLogs struct {
+	*observer.ObservedLogs
+}
+
+// ObserveForTest 仅供测试使用，将普通日志和错误日志都替换为捕获enab及以上级别的logger，返回恢复原logger的函数
+func ObserveForTest(enab zapcore.LevelEnabler) (logs ObservedLogs, restore func()) {
+	core, observed := observer.New(enab)
+	return ObservedLogs{observed}, replaceLoggers(zap.New(namedLevelCore{core}, zap.AddCaller(), zap.WithClock(packageClock{})))
+}
+
+// WithTemporaryLogger 将普通日志和错误日志都替换为l后执行fn，fn返回或panic后恢复原logger，
+// 替换的是包级全局变量，执行期间其他goroutine记录的日志同样写入l，不能在并发的测试中使用
+func WithTemporaryLogger(l *zap.Logger, fn func()) {
+	restore := replaceLoggers(l)
+	defer restore()
+	fn()
+}
+
+// replaceLoggers 将普通日志和错误日志都替换为l，返回恢复原logger的函数
+func replaceLoggers(l *zap.Logger) (restore func()) {
+	oldLogger, oldSugar, oldErr, oldSugarErr := logger, sugarLogger, errLogger, sugarErrLogger
+	logger = l
+	sugarLogger = logger.Sugar()
+	errLogger = logger
+	sugarErrLogger = sugarLogger
+	undoGlobals := zap.ReplaceGlobals(logger)
+	return func() {
+		logger, sugarLogger, errLogger, sugarErrLogger = oldLogger, oldSugar, oldErr, oldSugarErr
+		undoGlobals()
+	}
+}
+
+// FindEntry 获取第一条消息为msg的日志
+func (o ObservedLogs) FindEntry(msg string) (observer.LoggedEntry, bool) {
+	for _, entry := range o.All() {
+		if entry.Message == msg {
+			return entry, true
+		}
+	}
+	return observer.LoggedEntry{}, false
+}
+
+// FieldValue 获取日志中key字段的值，整数统一为int64或uint64，浮点数统一为float64
+func FieldValue(entry observer.LoggedEntry, key string) (interface{}, bool) {
+	for _, f := range entry.Context {
+		if f.Key == key {
+			m := zapcore.NewMapObjectEncoder()
+			f.AddTo(m)
+			return normalizeNumber(m.Fields[key]), true
+		}
+	}
+	return nil, false
+}
+
+// FieldString 获取字符串类型的字段
+func FieldString(entry observer.LoggedEntry, key string) (string, bool) {
+	v, _ := FieldValue(entry, key)
+	s, ok := v.(string)
+	return s, ok
+}
+
+// FieldInt 获取有符号整数类型的字段
+func FieldInt(entry observer.LoggedEntry, key string) (int64, bool) {
+	v, _ := FieldValue(entry, key)
+	i, ok := v.(int64)
+	return i, ok
+}
+
+// FieldBool 获取布尔类型的字段
+func FieldBool(entry observer.LoggedEntry, key string) (bool, bool) {
+	v, _ := FieldValue(entry, key)
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// FieldDuration 获取时长类型的字段
+func FieldDuration(entry observer.LoggedEntry, key string) (time.Duration, bool) {
+	v, _ := FieldValue(entry, key)
+	d, ok := v.(time.Duration)
+	return d, ok
+}
+
+// AssertFieldEquals 断言日志中key字段的值等于want，如AssertFieldEquals(t, entry, "status", 200)，
+// 比较前整数和浮点数统一宽度，200与zap.Int记录的int64(200)视为相等
+func AssertFieldEquals(t TestingT, entry observer.LoggedEntry, key string, want interface{}) bool {
+	t.Helper()
+	got, ok := FieldValue(entry, key)
+	if !ok {
+		t.Errorf("log entry %q has no field %q", entry.Message, key)
+		return false
+	}
+	want = normalizeNumber(want)
+	if _, ok := got.(uint64); ok {
+		// 无符号字段与非负的整数字面量比较
+		if i, ok := want.(int64); ok && i >= 0 {
+			want = uint64(i)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("log entry %q field %q = %#v (%T), want %#v (%T)", entry.Message, key, got, got, want, want)
+		return false
+	}
+	return true
+}
+
+// normalizeNumber 将各种宽度的整数和浮点数转换为int64、uint64和float64
+func normalizeNumber(v interface{}) interface{} {
+	if _, ok := v.(time.Duration); ok {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return v
+}
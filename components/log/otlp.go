@@ -0,0 +1,354 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	@func: 通过OTLP/HTTP协议将日志导出到OpenTelemetry Collector
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// OTLPConfig OTLP输出的配置，零值字段使用默认值
+type OTLPConfig struct {
+	// Endpoint Collector的OTLP/HTTP地址，如http://127.0.0.1:4318，日志发送到其/v1/logs
+	Endpoint string
+	// Headers 每次请求附带的请求头，如认证信息
+	Headers map[string]string
+	// ServiceName 资源属性service.name
+	ServiceName string
+	// ResourceAttributes 其他资源属性，如deployment.environment
+	ResourceAttributes map[string]string
+	// Level 导出的最低级别，零值即info
+	Level zapcore.Level
+	// BatchSize 每次请求最多的条数，默认512
+	BatchSize int
+	// FlushInterval 未攒满一批时的最长发送间隔，默认1s
+	FlushInterval time.Duration
+	// QueueSize 等待发送的日志队列长度，队列满时丢弃并计入DroppedBySink，默认10000
+	QueueSize int
+	// MaxRetries 每批最多重试次数，默认3
+	MaxRetries int
+	// RetryBackoff 首次重试前的等待时间，之后每次翻倍，默认1s
+	RetryBackoff time.Duration
+	// Timeout 单次请求的超时，默认10s
+	Timeout time.Duration
+}
+
+// OTLPCore 以OTLP/HTTP JSON批量导出日志的zapcore.Core，只依赖标准库，通过LoggerConfig.Cores加入logger
+type OTLPCore struct {
+	zapcore.LevelEnabler
+	fields []zapcore.Field
+	sink   *otlpSink
+}
+
+// otlpSink 由With派生出的各个core共享的发送队列
+type otlpSink struct {
+	*batchSink[otlpRecord]
+	conf     OTLPConfig
+	client   *http.Client
+	resource otlpResource
+}
+
+// otlp开头的类型对应OTLP logs协议JSON编码中的结构
+type otlpValue struct {
+	StringValue *string        `json:"stringValue,omitempty"`
+	BoolValue   *bool          `json:"boolValue,omitempty"`
+	IntValue    *int64         `json:"intValue,omitempty"`
+	DoubleValue *float64       `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArray     `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKeyValues `json:"kvlistValue,omitempty"`
+}
+
+type otlpArray struct {
+	Values []otlpValue `json:"values"`
+}
+
+type otlpKeyValues struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpValue      `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope    `json:"scope"`
+	LogRecords []otlpRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// NewOTLPCore 创建OTLP输出并启动发送goroutine，写入不会阻塞，退出前需调用Close，
+// 仅支持OTLP/HTTP的JSON编码，不依赖OpenTelemetry SDK
+func NewOTLPCore(conf OTLPConfig) (*OTLPCore, error) {
+	if conf.Endpoint == "" {
+		return nil, fmt.Errorf("log: otlp endpoint is required")
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 512
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 10000
+	}
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = time.Second
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+	s := &otlpSink{
+		conf:     conf,
+		client:   &http.Client{Timeout: conf.Timeout},
+		resource: otlpResource{Attributes: resourceAttributes(conf)},
+	}
+	s.batchSink = newBatchSink(batchSinkConfig{
+		name:          "otlp",
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		queueSize:     conf.QueueSize,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+	}, s.encode, s.sendOnce)
+	return &OTLPCore{LevelEnabler: conf.Level, sink: s}, nil
+}
+
+// resourceAttributes service.name及其他资源属性，按key排序
+func resourceAttributes(conf OTLPConfig) []otlpKeyValue {
+	attrs := map[string]string{}
+	for k, v := range conf.ResourceAttributes {
+		attrs[k] = v
+	}
+	if conf.ServiceName != "" {
+		attrs["service.name"] = conf.ServiceName
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpString(attrs[k])})
+	}
+	return kvs
+}
+
+func (c *OTLPCore) With(fields []zapcore.Field) zapcore.Core {
+	return &OTLPCore{LevelEnabler: c.LevelEnabler, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...), sink: c.sink}
+}
+
+func (c *OTLPCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *OTLPCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	if ent.LoggerName != "" {
+		enc.AddString("logger", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		enc.AddString("code.filepath", ent.Caller.File)
+		enc.AddInt("code.lineno", ent.Caller.Line)
+	}
+	if ent.Stack != "" {
+		enc.AddString("exception.stacktrace", ent.Stack)
+	}
+	c.sink.enqueue(otlpRecord{
+		TimeUnixNano:   strconv.FormatInt(ent.Time.UnixNano(), 10),
+		SeverityNumber: otlpSeverity(ent.Level),
+		SeverityText:   ent.Level.CapitalString(),
+		Body:           otlpString(ent.Message),
+		Attributes:     otlpKeyValuesOf(enc.Fields).Values,
+	})
+	return nil
+}
+
+// Sync 等待队列中已有的日志发送完成
+func (c *OTLPCore) Sync() error {
+	c.sink.sync()
+	return nil
+}
+
+// Close 停止发送goroutine，发送完队列中剩余的日志后返回
+func (c *OTLPCore) Close() error {
+	c.sink.close()
+	return nil
+}
+
+// otlpSeverity zap级别对应的OTLP SeverityNumber
+func otlpSeverity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 21
+	case zapcore.FatalLevel:
+		return 24
+	}
+	return 0
+}
+
+func otlpString(s string) otlpValue {
+	return otlpValue{StringValue: &s}
+}
+
+// otlpValueOf 将MapObjectEncoder得到的值转换为OTLP的AnyValue
+func otlpValueOf(v interface{}) otlpValue {
+	switch v := normalizeNumber(v).(type) {
+	case string:
+		return otlpString(v)
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int64:
+		return otlpValue{IntValue: &v}
+	case uint64:
+		if v <= math.MaxInt64 {
+			i := int64(v)
+			return otlpValue{IntValue: &i}
+		}
+		return otlpString(strconv.FormatUint(v, 10))
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	case time.Duration:
+		return otlpString(v.String())
+	case time.Time:
+		return otlpString(v.Format(time.RFC3339Nano))
+	case []interface{}:
+		values := make([]otlpValue, len(v))
+		for i, item := range v {
+			values[i] = otlpValueOf(item)
+		}
+		return otlpValue{ArrayValue: &otlpArray{Values: values}}
+	case map[string]interface{}:
+		return otlpValue{KvlistValue: otlpKeyValuesOf(v)}
+	case nil:
+		return otlpString("")
+	default:
+		// zap.Any等反射值先转为JSON结构，再按数组、对象转换
+		if data, err := json.Marshal(v); err == nil {
+			var decoded interface{}
+			if json.Unmarshal(data, &decoded) == nil {
+				if _, ok := decoded.(float64); ok || decoded == nil {
+					return otlpString(string(data))
+				}
+				return otlpValueOf(decoded)
+			}
+		}
+		return otlpString(fmt.Sprint(v))
+	}
+}
+
+// otlpKeyValuesOf 按key排序转换为OTLP的键值对
+func otlpKeyValuesOf(m map[string]interface{}) *otlpKeyValues {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := &otlpKeyValues{Values: make([]otlpKeyValue, 0, len(keys))}
+	for _, k := range keys {
+		kvs.Values = append(kvs.Values, otlpKeyValue{Key: k, Value: otlpValueOf(m[k])})
+	}
+	return kvs
+}
+
+// encode 将一批日志编码为导出请求
+func (s *otlpSink) encode(batch []otlpRecord) ([]byte, error) {
+	return json.Marshal(otlpRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource: s.resource,
+		ScopeLogs: []otlpScopeLogs{{
+			Scope:      otlpScope{Name: "go_components_record/components/log"},
+			LogRecords: batch,
+		}},
+	}}})
+}
+
+// sendOnce 发送一次导出请求，返回是否值得重试
+func (s *otlpSink) sendOnce(body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.conf.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.conf.Endpoint, "/")+"/v1/logs", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		io.Copy(io.Discard, resp.Body)
+		return true, fmt.Errorf("log: otlp export returned %s", resp.Status)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("log: otlp export returned %s: %s", resp.Status, msg)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return false, nil
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+/*
+	@func: 只记录panic的独立日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+var panicLogger = zap.NewNop()
+
+// initPanicLogger 启用时创建只写panic日志文件的logger，无法打开文件时返回错误，直接同步写入文件
+func initPanicLogger(conf LoggerConfig, encoder zapcore.Encoder) error {
+	if !conf.PanicLog {
+		panicLogger = zap.NewNop()
+		return nil
+	}
+	writer, err := getLogWriter("-panic.log", conf.PanicRotate, conf.NoSymlink)
+	if err != nil {
+		return err
+	}
+	panicLogger = zap.New(zapcore.NewCore(encoder, writer, zapcore.ErrorLevel), zap.AddCaller(), zap.WithClock(packageClock{}))
+	return nil
+}
+
+// logPanic 将panic同时写入错误日志和panic日志，调用位置为logPanic的调用方
+func logPanic(msg string, fields ...zap.Field) {
+	errLogger.WithOptions(zap.AddCallerSkip(1)).Error(msg, fields...)
+	panicLogger.WithOptions(zap.AddCallerSkip(1)).Error(msg, fields...)
+}
+
+// Panic 将msg写入错误日志和panic日志(启用PanicLog时)后以msg panic
+func Panic(msg string, fields ...zap.Field) {
+	panicLogger.WithOptions(zap.AddCallerSkip(1)).Error(msg, fields...)
+	errLogger.WithOptions(zap.AddCallerSkip(1)).Panic(msg, fields...)
+}
+
+// Fatal 将msg写入错误日志和panic日志(启用PanicLog时)后退出进程
+func Fatal(msg string, fields ...zap.Field) {
+	panicLogger.WithOptions(zap.AddCallerSkip(1)).Error(msg, fields...)
+	errLogger.WithOptions(zap.AddCallerSkip(1)).Fatal(msg, fields...)
+}
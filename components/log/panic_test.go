@@ -0,0 +1,64 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPanicRetentionSurvivesMainCleanup(t *testing.T) {
+	chdirTemp(t)
+	mainOld := "log/zap-20200101-0000.log"
+	panicKept := "log/panic/zap-20200101-0000-panic.log"
+	writeAged(t, mainOld, 30*24*time.Hour)
+	writeAged(t, panicKept, 30*24*time.Hour)
+
+	if err := InitLoggerWithConfig(LoggerConfig{Env: "test", DisableStdout: true, NoSymlink: true, PanicLog: true}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinRecovery(false))
+	r.GET("/boom", func(c *gin.Context) { panic("boom") })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	logger.Info("trigger main rotation")
+
+	waitRemoved(t, mainOld)
+	if _, err := os.Stat(panicKept); err != nil {
+		t.Fatalf("panic log removed by main MaxAge: %v", err)
+	}
+	files, _ := filepath.Glob("log/panic/zap-*-panic.log")
+	var found bool
+	for _, f := range files {
+		if data, _ := os.ReadFile(f); strings.Contains(string(data), "boom") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("panic was not written to the panic log, files: %v", files)
+	}
+}
+
+func TestPanicOpenFailureReturnsError(t *testing.T) {
+	chdirTemp(t)
+	if err := os.MkdirAll("log", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("log/panic", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitLoggerWithConfig(LoggerConfig{DisableStdout: true, NoSymlink: true, PanicLog: true}); err == nil {
+		t.Fatal("InitLoggerWithConfig succeeded with an unwritable panic directory")
+	}
+}
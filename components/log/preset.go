@@ -0,0 +1,60 @@
+package log
+
+import "fmt"
+
+/*
+	@func: 按环境预设的日志配置
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// Preset 预设名
+type Preset string
+
+const (
+	// PresetDevelopment 本地开发，console输出到标准输出，debug级别，不写文件
+	PresetDevelopment Preset = "development"
+	// PresetStaging 预发环境，写文件并保留debug日志，标准输出使用json
+	PresetStaging Preset = "staging"
+	// PresetProduction 生产环境，json写文件，info级别并对普通日志采样，不输出到标准输出
+	PresetProduction Preset = "production"
+	// PresetCI 持续集成，json输出到标准输出，不写文件，便于收集
+	PresetCI Preset = "ci"
+)
+
+var presets = map[Preset]LoggerConfig{
+	PresetDevelopment: {
+		Level:          "debug",
+		StdoutEncoding: EncodingConsole,
+	},
+	PresetStaging: {
+		Env:            "test",
+		Level:          "debug",
+		StdoutEncoding: EncodingJSON,
+		FileEncoding:   EncodingJSON,
+	},
+	PresetProduction: {
+		Env:           "prod",
+		Level:         "info",
+		DisableStdout: true,
+		FileEncoding:  EncodingJSON,
+		Sampling:      &SamplingConfig{Initial: 100, Thereafter: 100},
+	},
+	PresetCI: {
+		Level:          "info",
+		StdoutEncoding: EncodingJSON,
+	},
+}
+
+// PresetConfig 获取预设的配置，返回的是副本，可修改后传给InitLoggerWithConfig
+func PresetConfig(name string) (LoggerConfig, error) {
+	conf, ok := presets[Preset(name)]
+	if !ok {
+		return LoggerConfig{}, fmt.Errorf("log: unknown preset %q", name)
+	}
+	if conf.Sampling != nil {
+		sampling := *conf.Sampling
+		conf.Sampling = &sampling
+	}
+	return conf, nil
+}
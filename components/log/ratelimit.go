@@ -0,0 +1,38 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+/*
+	@func: 限流事件的日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// RateLimitFields 限流日志的统一字段
+func RateLimitFields(ip, endpoint string, limit, count int64) []zap.Field {
+	return []zap.Field{
+		zap.String("ip", ip),
+		zap.String("endpoint", endpoint),
+		zap.Int64("limit", limit),
+		zap.Int64("count", count),
+	}
+}
+
+// RateLimitLog count超过limit即请求被限流时以Warn记录，未超过时不记录，可在每次请求时直接调用
+// endpoint优先使用路由模板c.FullPath，未匹配路由时使用请求路径，c或c.Request为nil时ip和endpoint为空
+func RateLimitLog(c *gin.Context, limit, count int64, fields ...zap.Field) {
+	if count <= limit {
+		return
+	}
+	var ip, endpoint string
+	if c != nil && c.Request != nil {
+		ip = c.ClientIP()
+		if endpoint = c.FullPath(); endpoint == "" && c.Request.URL != nil {
+			endpoint = c.Request.URL.Path
+		}
+	}
+	logger.WithOptions(zap.AddCallerSkip(1)).Warn("[rate limited]", append(RateLimitFields(ip, endpoint, limit, count), fields...)...)
+}
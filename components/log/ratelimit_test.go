@@ -0,0 +1,21 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"testing"
+)
+
+func TestRateLimitLogReportsCallerOfRateLimitLog(t *testing.T) {
+	logs, restore := ObserveForTest(zapcore.InfoLevel)
+	defer restore()
+
+	RateLimitLog(nil, 10, 11)
+	entry, ok := logs.FindEntry("[rate limited]")
+	if !ok {
+		t.Fatal("no rate limit entry")
+	}
+	if !strings.HasSuffix(entry.Caller.File, "ratelimit_test.go") {
+		t.Fatalf("caller = %s, want ratelimit_test.go", entry.Caller.File)
+	}
+}
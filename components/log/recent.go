@@ -0,0 +1,102 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	@func: 在内存中保留最近的日志，供管理接口查看
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// logRing 保留最近size条编码后的日志，作为core的WriteSyncer，每次Write为一条日志
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newLogRing(size int) *logRing {
+	return &logRing{lines: make([]string, size)}
+}
+
+func (r *logRing) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\r\n")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+	return len(p), nil
+}
+
+func (r *logRing) Sync() error {
+	return nil
+}
+
+// snapshot 按时间从旧到新复制保留的日志
+func (r *logRing) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	if r.full {
+		out = append(out, r.lines[r.next:]...)
+	}
+	return append(out, r.lines[:r.next]...)
+}
+
+// recentRing 当前使用的logRing，未启用RecentLogs时为nil
+var recentRing atomic.Pointer[logRing]
+
+// useLogRing 按size获取logRing，大小不变时沿用已有的，重新加载配置后不丢失已保留的日志
+func useLogRing(size int) *logRing {
+	if size <= 0 {
+		recentRing.Store(nil)
+		return nil
+	}
+	if r := recentRing.Load(); r != nil && len(r.lines) == size {
+		return r
+	}
+	r := newLogRing(size)
+	recentRing.Store(r)
+	return r
+}
+
+// RecentLogs 最近的日志，按时间从旧到新排列，每条为按FileEncoding编码的一行，未启用LoggerConfig.RecentLogs时返回nil
+func RecentLogs() []string {
+	r := recentRing.Load()
+	if r == nil {
+		return nil
+	}
+	return r.snapshot()
+}
+
+// RecentLogsHandler 以纯文本返回最近的日志，每行一条，可用?n=100只返回最新的100条，
+// 会暴露日志内容，需挂载在有鉴权的管理路由下
+func RecentLogsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if recentRing.Load() == nil {
+			c.String(http.StatusNotFound, "recent logs are not enabled\n")
+			return
+		}
+		lines := RecentLogs()
+		if n, err := strconv.Atoi(c.Query("n")); err == nil && n >= 0 && n < len(lines) {
+			lines = lines[len(lines)-n:]
+		}
+		var b strings.Builder
+		for _, line := range lines {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(b.String()))
+	}
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strings"
+)
+
+/*
+	@func: 为每个请求绑定带有请求ID、链路ID的logger
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+const requestLoggerKey = "go_components_record/log.request"
+
+// TraceparentHeader W3C Trace Context传递链路信息的请求头
+const TraceparentHeader = "traceparent"
+
+// GinRequestLogger 为每个请求创建带有request_id及traceparent中trace_id、span_id字段的logger，
+// 通过L(c)获取，同时绑定到c.Request的context上，可通过LoggerFromContext获取，
+// 放在GinDebugBuffer、GinFlightRecorder之后时基于它们安装的logger
+func GinRequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var fields []zap.Field
+		if id := requestID(c); id != "" {
+			fields = append(fields, zap.String("request_id", id))
+		}
+		if traceID, spanID, ok := parseTraceparent(c.GetHeader(TraceparentHeader)); ok {
+			fields = append(fields, zap.String("trace_id", traceID), zap.String("span_id", spanID))
+		}
+		l := BufferedLogger(c).With(fields...)
+		c.Set(requestLoggerKey, l)
+		c.Request = c.Request.WithContext(ContextWithLogger(c.Request.Context(), l))
+		c.Next()
+	}
+}
+
+// L 获取GinRequestLogger安装的请求级logger，未安装时返回BufferedLogger(c)，即没有中间件时为全局logger
+func L(c *gin.Context) *zap.Logger {
+	if c == nil {
+		return logger
+	}
+	if l, ok := c.Get(requestLoggerKey); ok {
+		return l.(*zap.Logger)
+	}
+	return BufferedLogger(c)
+}
+
+// parseTraceparent 解析version-trace_id-parent_id-flags格式的traceparent，全0的ID视为无效
+func parseTraceparent(header string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if !isLowerHex(parts[1]) || !isLowerHex(parts[2]) ||
+		strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
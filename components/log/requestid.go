@@ -0,0 +1,86 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"sync"
+)
+
+/*
+	@func: 检测并发请求中重复的请求ID
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// defaultMaxTrackedRequestIDs 默认最多同时跟踪的请求ID数
+const defaultMaxTrackedRequestIDs = 10000
+
+// inFlightRequestIDs 处理中的请求ID及其并发数，请求结束时移除，超过max个时不再跟踪新的ID
+type inFlightRequestIDs struct {
+	mu  sync.Mutex
+	ids map[string]int
+	max int
+}
+
+func newInFlightRequestIDs(max int) *inFlightRequestIDs {
+	if max <= 0 {
+		max = defaultMaxTrackedRequestIDs
+	}
+	return &inFlightRequestIDs{ids: map[string]int{}, max: max}
+}
+
+// add 记录id，返回id是否已在处理中，以及是否被跟踪，被跟踪的id需在请求结束时调用remove
+func (s *inFlightRequestIDs) add(id string) (dup, tracked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n, ok := s.ids[id]; ok {
+		s.ids[id] = n + 1
+		return true, true
+	}
+	if len(s.ids) >= s.max {
+		return false, false
+	}
+	s.ids[id] = 1
+	return false, true
+}
+
+func (s *inFlightRequestIDs) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := s.ids[id]; n > 1 {
+		s.ids[id] = n - 1
+	} else {
+		delete(s.ids, id)
+	}
+}
+
+// requestID 读取请求头中的请求ID，没有时读取请求context上绑定的请求ID
+func requestID(c *gin.Context) string {
+	if id := c.GetHeader(RequestIDHeader); id != "" {
+		return id
+	}
+	return RequestIDFromContext(c.Request.Context())
+}
+
+// trackRequestID 跟踪请求ID，与处理中的请求重复时以Warn记录，返回请求结束时需调用的函数
+func trackRequestID(c *gin.Context, ids *inFlightRequestIDs, conf GinLoggerConfig) func() {
+	id := requestID(c)
+	if id == "" {
+		return noopDone
+	}
+	dup, tracked := ids.add(id)
+	if dup {
+		WithoutCaller().Warn("[duplicate request id]",
+			zap.String("request_id", id),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("ip", conf.ClientIP(c)),
+		)
+	}
+	if !tracked {
+		return noopDone
+	}
+	return func() {
+		ids.remove(id)
+	}
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+	@func: 记录重试
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// RetryFields 重试日志的字段，attempt为即将进行的第几次重试，从1开始，delay为重试前的等待时间
+func RetryFields(attempt int, delay time.Duration, err error) []zap.Field {
+	return []zap.Field{
+		zap.Int("attempt", attempt),
+		zap.Duration("delay", delay),
+		zap.Error(err),
+	}
+}
+
+// RetryLog 以Warn记录一次重试及触发重试的错误，消息均为"[retry]"，便于统计重试频率
+func RetryLog(attempt int, delay time.Duration, err error, fields ...zap.Field) {
+	logger.WithOptions(zap.AddCallerSkip(1)).Warn("[retry]", append(RetryFields(attempt, delay, err), fields...)...)
+}
+
+// RetryFailed 重试attempts次后仍然失败时以Error写入错误日志，消息为"[retry exhausted]"，与中间的重试区分
+func RetryFailed(attempts int, err error, fields ...zap.Field) {
+	errLogger.WithOptions(zap.AddCallerSkip(1)).Error("[retry exhausted]", append([]zap.Field{
+		zap.Int("attempts", attempts),
+		zap.Error(err),
+	}, fields...)...)
+}
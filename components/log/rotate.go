@@ -0,0 +1,120 @@
+package log
+
+import (
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"go.uber.org/zap"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+	@func: 日志文件切换后的处理
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// rotationHandler 每次切换到新文件后执行，rotatelogs会在单独的goroutine中调用
+func rotationHandler(suffix string, rotate RotateConfig) rotatelogs.Handler {
+	return rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+		ev, ok := e.(*rotatelogs.FileRotatedEvent)
+		if !ok {
+			return
+		}
+		// 先写入校验文件，归档时一并上传
+		var sumPath string
+		if rotate.Checksum && ev.PreviousFile() != "" {
+			var err error
+			if sumPath, err = writeChecksum(ev.PreviousFile()); err != nil {
+				errLogger.Error("log: write checksum failed", zap.String("file", ev.PreviousFile()), zap.Error(err))
+			}
+		}
+		if a := currentArchiver.Load(); a != nil && ev.PreviousFile() != "" {
+			a.enqueue(ev.PreviousFile())
+			if sumPath != "" {
+				a.enqueue(sumPath)
+			}
+		}
+		if rotate.MaxBackups > 0 {
+			pruneBackups(suffix, ev.CurrentFile(), rotate.MaxBackups)
+		}
+		if rotate.Checksum {
+			pruneChecksums(suffix)
+		}
+	})
+}
+
+// dateDirDepth 按日期分目录时年、月、日三级目录
+const dateDirDepth = 3
+
+// backup 一个日志文件及其修改时间
+type backup struct {
+	path    string
+	modTime int64
+}
+
+// backupFiles 后缀为suffix的所有日志文件，包括日期目录中的文件，按修改时间从旧到新排列
+func backupFiles(suffix string) []string {
+	pattern := regexp.MustCompile(`^zap-\d{8}-\d{4}` + regexp.QuoteMeta(suffix) + `(\.\d+)?$`)
+	backups := findBackups(streamDir(suffix), pattern, dateDirDepth)
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime < backups[j].modTime
+	})
+	files := make([]string, len(backups))
+	for i, b := range backups {
+		files[i] = b.path
+	}
+	return files
+}
+
+// findBackups 在dir及其下最多depth级子目录中查找名称匹配pattern的文件
+func findBackups(dir string, pattern *regexp.Regexp, depth int) []backup {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var backups []backup
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if depth > 0 {
+				backups = append(backups, findBackups(filepath.Join(dir, entry.Name()), pattern, depth-1)...)
+			}
+			continue
+		}
+		if !pattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, entry.Name()), modTime: info.ModTime().UnixNano()})
+	}
+	return backups
+}
+
+// removeEmptyDirs 删除dir及其上级直到root之间的空目录，非空目录删除失败即停止
+func removeEmptyDirs(root, dir string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if fsys.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// pruneBackups 除当前文件外最多保留maxBackups个文件，超出时删除最旧的
+func pruneBackups(suffix, current string, maxBackups int) {
+	var backups []string
+	for _, f := range backupFiles(suffix) {
+		if filepath.Clean(f) != filepath.Clean(current) {
+			backups = append(backups, f)
+		}
+	}
+	for i := 0; i < len(backups)-maxBackups; i++ {
+		if fsys.Remove(backups[i]) == nil {
+			removeEmptyDirs(streamDir(suffix), filepath.Dir(backups[i]))
+		}
+	}
+}
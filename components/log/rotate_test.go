@@ -0,0 +1,95 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// chdirTemp 切换到临时目录，使logDir指向其中的log目录
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// writeAged 创建修改时间为age之前的日志文件
+func writeAged(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-age)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitRemoved 等待rotatelogs在后台goroutine中删除path
+func waitRemoved(t *testing.T, path string) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return
+		}
+	}
+	t.Fatalf("%s was not removed by MaxAge cleanup", path)
+}
+
+func openTestWriter(t *testing.T, suffix string, maxAge time.Duration) {
+	t.Helper()
+	w, err := getWriter(suffix, RotateConfig{RotationTime: time.Minute, MaxAge: maxAge}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.(io.Closer).Close() })
+	if _, err := w.Write([]byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamDir(t *testing.T) {
+	tests := []struct {
+		suffix string
+		want   string
+	}{
+		{".log", "./log"},
+		{"-error.log", "./log/error"},
+		{"-audit.log", "./log/audit"},
+		{"-panic.log", "./log/panic"},
+		{"-slow.log", "./log/slow"},
+	}
+	for _, tt := range tests {
+		if got := streamDir(tt.suffix); got != tt.want {
+			t.Errorf("streamDir(%q) = %q, want %q", tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestMaxAgeCleanupDoesNotCrossStreams(t *testing.T) {
+	chdirTemp(t)
+	mainOld := "log/zap-20200101-0000.log"
+	errorKept := "log/error/zap-20200101-0000-error.log"
+	writeAged(t, mainOld, 10*24*time.Hour)
+	writeAged(t, errorKept, 10*24*time.Hour)
+
+	// 普通日志保留7天，错误日志保留90天
+	openTestWriter(t, ".log", 7*24*time.Hour)
+	openTestWriter(t, "-error.log", 90*24*time.Hour)
+
+	waitRemoved(t, mainOld)
+	if _, err := os.Stat(errorKept); err != nil {
+		t.Fatalf("error log removed by main MaxAge: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"runtime"
+	"time"
+)
+
+/*
+	@func: 定时输出Go运行时的内存、goroutine和GC统计
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// defaultRuntimeStatsInterval 默认的运行时统计输出间隔
+const defaultRuntimeStatsInterval = time.Minute
+
+// StartRuntimeStats 每隔interval以Info输出一条runtime stats日志，包含堆内存、goroutine数及GC次数和停顿，
+// interval<=0时为1分钟，返回的stop函数会等待后台goroutine退出，可重复调用
+func StartRuntimeStats(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = defaultRuntimeStatsInterval
+	}
+	var last runtime.MemStats
+	runtime.ReadMemStats(&last)
+	return startTicker(interval, func() {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		WithoutCaller().Info("runtime stats", runtimeStatsFields(&m, &last)...)
+		last = m
+	})
+}
+
+// runtimeStatsFields 当前的内存和goroutine统计，以及与上次统计之间的GC次数、停顿总时长和最大停顿
+func runtimeStatsFields(m, last *runtime.MemStats) []zap.Field {
+	var maxPause uint64
+	gcs := m.NumGC - last.NumGC
+	if gcs > uint32(len(m.PauseNs)) {
+		gcs = uint32(len(m.PauseNs))
+	}
+	for i := uint32(0); i < gcs; i++ {
+		if p := m.PauseNs[(m.NumGC-i+255)%256]; p > maxPause {
+			maxPause = p
+		}
+	}
+	return []zap.Field{
+		zap.Int("goroutines", runtime.NumGoroutine()),
+		zap.Uint64("heap_alloc", m.HeapAlloc),
+		zap.Uint64("heap_inuse", m.HeapInuse),
+		zap.Uint64("heap_objects", m.HeapObjects),
+		zap.Uint64("sys", m.Sys),
+		zap.Uint32("num_gc", m.NumGC),
+		zap.Uint32("gc_count", m.NumGC-last.NumGC),
+		zap.Duration("gc_pause", time.Duration(m.PauseTotalNs-last.PauseTotalNs)),
+		zap.Duration("gc_pause_max", time.Duration(maxPause)),
+	}
+}
@@ -0,0 +1,192 @@
+package log
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+	@func: 上传到S3或兼容S3的存储，使用标准库实现签名，不依赖AWS SDK
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// S3Config S3上传的配置，凭证从环境变量AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY、AWS_SESSION_TOKEN读取
+type S3Config struct {
+	// Bucket 存储桶
+	Bucket string
+	// Region 区域，为空时读取环境变量AWS_REGION
+	Region string
+	// Endpoint 兼容S3的存储地址，如http://minio:9000，设置后使用path-style访问
+	Endpoint string
+}
+
+// s3Uploader 以AWS Signature V4签名的PUT请求上传对象
+type s3Uploader struct {
+	conf         S3Config
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+}
+
+// NewS3Archiver 创建上传到S3的归档器并注册，日志文件切换后上一个文件会被异步上传
+func NewS3Archiver(s3 S3Config, conf ArchiveConfig) (*Archiver, error) {
+	uploader, err := NewS3Uploader(s3)
+	if err != nil {
+		return nil, err
+	}
+	a := NewArchiver(uploader, conf)
+	EnableArchive(a)
+	return a, nil
+}
+
+// NewS3Uploader 创建上传到S3的Uploader
+func NewS3Uploader(conf S3Config) (Uploader, error) {
+	if conf.Region == "" {
+		conf.Region = os.Getenv("AWS_REGION")
+	}
+	u := &s3Uploader{
+		conf:         conf,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{},
+	}
+	switch {
+	case conf.Bucket == "":
+		return nil, errors.New("log: s3 bucket is required")
+	case conf.Region == "":
+		return nil, errors.New("log: s3 region is required")
+	case u.accessKey == "" || u.secretKey == "":
+		return nil, errors.New("log: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+	}
+	return u, nil
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, key string, file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+
+	host, uri := u.location(key)
+	scheme := "https"
+	if strings.HasPrefix(u.conf.Endpoint, "http://") {
+		scheme = "http"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, scheme+"://"+host+uri, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	u.sign(req, host, uri, payloadHash, time.Now().UTC())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("log: s3 upload %s: %s: %s", key, resp.Status, body)
+	}
+	return nil
+}
+
+// location 返回请求的host和编码后的路径，设置Endpoint时使用path-style
+func (u *s3Uploader) location(key string) (host, uri string) {
+	if u.conf.Endpoint != "" {
+		host = strings.TrimPrefix(strings.TrimPrefix(u.conf.Endpoint, "https://"), "http://")
+		return strings.TrimSuffix(host, "/"), "/" + s3Escape(u.conf.Bucket) + "/" + s3Escape(key)
+	}
+	return fmt.Sprintf("%s.s3.%s.amazonaws.com", u.conf.Bucket, u.conf.Region), "/" + s3Escape(key)
+}
+
+// sign 添加Signature V4所需的请求头
+func (u *s3Uploader) sign(req *http.Request, host, uri, payloadHash string, t time.Time) {
+	headers := map[string]string{
+		"host":                 host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           t.Format("20060102T150405Z"),
+	}
+	if u.sessionToken != "" {
+		headers["x-amz-security-token"] = u.sessionToken
+	}
+	for name, value := range headers {
+		if name != "host" {
+			req.Header.Set(name, value)
+		}
+	}
+	scope := t.Format("20060102") + "/" + u.conf.Region + "/s3/aws4_request"
+	signedHeaders, signature := sigV4(u.secretKey, u.conf.Region, req.Method, uri, "", headers, payloadHash, t)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		u.accessKey, scope, signedHeaders, signature))
+}
+
+// sigV4 计算S3的Signature V4签名，headers的key为小写的请求头名且全部参与签名，
+// query为已编码并按参数名排序的查询串
+func sigV4(secretKey, region, method, uri, query string, headers map[string]string, payloadHash string, t time.Time) (signedHeaders, signature string) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{method, uri, query, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	return signedHeaders, hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape 按RFC 3986编码对象名，保留/
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,203 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+	@func: 限制深度和大小的任意值字段
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// SafeAnyLimits SafeAny的限制，<=0的项使用默认值
+type SafeAnyLimits struct {
+	// MaxDepth 结构体、map、slice、指针和接口的最大嵌套层数，默认5
+	MaxDepth int
+	// MaxItems 每个map、slice最多输出的元素数，默认50
+	MaxItems int
+	// MaxBytes 序列化后的最大字节数，超出时截断为字符串，默认4096
+	MaxBytes int
+}
+
+var defaultSafeAnyLimits = SafeAnyLimits{MaxDepth: 5, MaxItems: 50, MaxBytes: 4096}
+
+var safeAnyLimits atomic.Pointer[SafeAnyLimits]
+
+// SetSafeAnyLimits 设置全局的SafeAny限制
+func SetSafeAnyLimits(limits SafeAnyLimits) {
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = defaultSafeAnyLimits.MaxDepth
+	}
+	if limits.MaxItems <= 0 {
+		limits.MaxItems = defaultSafeAnyLimits.MaxItems
+	}
+	if limits.MaxBytes <= 0 {
+		limits.MaxBytes = defaultSafeAnyLimits.MaxBytes
+	}
+	safeAnyLimits.Store(&limits)
+}
+
+// SafeAny 类似zap.Any，但按SafeAnyLimits限制嵌套层数、元素数和序列化后的大小，
+// 超出层数的部分输出为"...", 超出元素数时追加剩余个数，用于记录外部传入的数据
+func SafeAny(key string, v interface{}) zap.Field {
+	limits := defaultSafeAnyLimits
+	if l := safeAnyLimits.Load(); l != nil {
+		limits = *l
+	}
+	pruned := pruneValue(reflect.ValueOf(v), limits, 0)
+	b, err := json.Marshal(pruned)
+	if err != nil {
+		return zap.String(key, truncateString(fmt.Sprintf("%+v", pruned), limits.MaxBytes))
+	}
+	if len(b) > limits.MaxBytes {
+		return zap.String(key, truncateString(string(b), limits.MaxBytes))
+	}
+	return zap.Reflect(key, json.RawMessage(b))
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// pruneValue 按限制复制v，返回可直接json序列化的值
+func pruneValue(v reflect.Value, limits SafeAnyLimits, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if v.CanInterface() {
+		if err, ok := v.Interface().(error); ok {
+			return err.Error()
+		}
+		if v.Type().Implements(jsonMarshalerType) {
+			b, err := json.Marshal(v.Interface())
+			if err != nil || len(b) > limits.MaxBytes {
+				return fmt.Sprintf("%v", v.Interface())
+			}
+			return json.RawMessage(b)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		// 指向自身的指针或接口同样受层数限制，避免无限递归
+		if depth >= limits.MaxDepth {
+			return "..."
+		}
+		return pruneValue(v.Elem(), limits, depth+1)
+	case reflect.Struct:
+		if depth >= limits.MaxDepth {
+			return "..."
+		}
+		out := make(map[string]interface{})
+		skipped := 0
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag := f.Tag.Get("json"); tag != "" {
+				if tag == "-" {
+					continue
+				}
+				if n, _, _ := strings.Cut(tag, ","); n != "" {
+					name = n
+				}
+			}
+			if len(out) >= limits.MaxItems {
+				skipped++
+				continue
+			}
+			out[name] = pruneValue(v.Field(i), limits, depth+1)
+		}
+		if skipped > 0 {
+			out["..."] = fmt.Sprintf("%d more", skipped)
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		if depth >= limits.MaxDepth {
+			return "..."
+		}
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		sort.Sort(keysByName{keys, names})
+		out := make(map[string]interface{}, len(keys))
+		for i, k := range keys {
+			if i >= limits.MaxItems {
+				out["..."] = fmt.Sprintf("%d more", len(keys)-i)
+				break
+			}
+			out[names[i]] = pruneValue(v.MapIndex(k), limits, depth+1)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return truncateString(string(b), limits.MaxBytes)
+		}
+		if depth >= limits.MaxDepth {
+			return "..."
+		}
+		n := v.Len()
+		out := make([]interface{}, 0, minInt(n, limits.MaxItems)+1)
+		for i := 0; i < n; i++ {
+			if i >= limits.MaxItems {
+				out = append(out, fmt.Sprintf("...(%d more)", n-i))
+				break
+			}
+			out = append(out, pruneValue(v.Index(i), limits, depth+1))
+		}
+		return out
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return v.Type().String()
+	case reflect.String:
+		return truncateString(v.String(), limits.MaxBytes)
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Complex())
+	}
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return fmt.Sprint(v)
+}
+
+// keysByName 按key的字符串形式排序map的key，使输出稳定
+type keysByName struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (k keysByName) Len() int           { return len(k.keys) }
+func (k keysByName) Less(i, j int) bool { return k.names[i] < k.names[j] }
+func (k keysByName) Swap(i, j int) {
+	k.keys[i], k.keys[j] = k.keys[j], k.keys[i]
+	k.names[i], k.names[j] = k.names[j], k.names[i]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"encoding/json"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"testing"
+)
+
+// selfPtr 指向自身的指针类型
+type selfPtr *selfPtr
+
+func safeAnyJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	enc := zapcore.NewMapObjectEncoder()
+	SafeAny("v", v).AddTo(enc)
+	switch out := enc.Fields["v"].(type) {
+	case json.RawMessage:
+		return string(out)
+	case string:
+		return out
+	default:
+		b, _ := json.Marshal(out)
+		return string(b)
+	}
+}
+
+func TestSafeAnySelfReferenceTerminates(t *testing.T) {
+	var p selfPtr
+	p = &p
+	var i interface{}
+	i = &i
+	type node struct {
+		Name string
+		Next interface{}
+	}
+	n := &node{Name: "a"}
+	n.Next = n
+
+	cases := map[string]interface{}{
+		"pointer":   p,
+		"interface": i,
+		"struct":    n,
+	}
+	for name, v := range cases {
+		if got := safeAnyJSON(t, v); !strings.Contains(got, `"..."`) {
+			t.Errorf("%s: got %s, want depth-limited output", name, got)
+		}
+	}
+}
+
+func TestSafeAnyLimits(t *testing.T) {
+	defer SetSafeAnyLimits(SafeAnyLimits{})
+	SetSafeAnyLimits(SafeAnyLimits{MaxItems: 2})
+	cases := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"slice", []int{1, 2, 3, 4}, `[1,2,"...(2 more)"]`},
+		{"map", map[string]int{"a": 1, "b": 2, "c": 3}, `{"...":"1 more","a":1,"b":2}`},
+		{"bytes", []byte("raw"), `"raw"`},
+		{"nil", nil, `null`},
+	}
+	for _, tc := range cases {
+		if got := safeAnyJSON(t, tc.v); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+)
+
+/*
+	@func: 初始化后的自检
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// selfTest 写入初始化标记，启用了文件输出时检查文件已创建且可写，尽早暴露权限或路径问题，
+// 标记与ContextWithLevel放行的日志一样跳过级别检查，Level为warn、error时同样写入
+func selfTest(conf LoggerConfig) error {
+	_, fileLevel, err := outputLevels(conf)
+	if err != nil {
+		return err
+	}
+	dropped := stats.droppedBySink.Load()
+	logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return contextLevelCore{Core: core, level: zapcore.DebugLevel}
+	})).Info("logger initialized",
+		zap.String("env", conf.Env),
+		zap.String("directory", logDir),
+		zap.String("level", fileLevel.String()),
+		zap.String("stdout_encoding", conf.StdoutEncoding),
+		zap.String("file_encoding", conf.FileEncoding),
+		zap.Bool("audit", conf.Audit),
+		zap.Bool("slow_log", conf.SlowLog),
+		zap.Bool("panic_log", conf.PanicLog),
+	)
+	rotateWritersMu.Lock()
+	writer, ok := rotateWriters[".log"]
+	rotateWritersMu.Unlock()
+	if !ok {
+		return nil
+	}
+	if stats.droppedBySink.Load() != dropped {
+		return fmt.Errorf("log: self test failed to write to %s", logDir)
+	}
+	// rotatelogs在首次写入时才创建文件，标记被SuppressMessages丢弃时以空写入创建
+	if _, err := writer.Write(nil); err != nil {
+		return fmt.Errorf("log: self test failed to open a log file in %s: %w", logDir, err)
+	}
+	filename := writer.CurrentFileName()
+	f, err := fsys.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("log: self test: log file %q is not writable: %w", filename, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,35 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSelfTestWritesMarkerAboveConfiguredLevel(t *testing.T) {
+	chdirTemp(t)
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+	if err := InitLoggerWithConfig(LoggerConfig{Env: "prod", Level: "warn", SelfTest: true, DisableStdout: true}); err != nil {
+		t.Fatal(err)
+	}
+	logger.Sync()
+	rotateWritersMu.Lock()
+	filename := rotateWriters[".log"].CurrentFileName()
+	rotateWritersMu.Unlock()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "logger initialized") || !strings.Contains(string(data), `"level": "warn"`) {
+		t.Fatalf("self test marker missing or without level: %q", data)
+	}
+}
+
+func TestSelfTestPassesWhenMarkerSuppressed(t *testing.T) {
+	chdirTemp(t)
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+	conf := LoggerConfig{Level: "error", SelfTest: true, DisableStdout: true, SuppressMessages: []string{"logger initialized"}}
+	if err := InitLoggerWithConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+}
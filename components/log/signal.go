@@ -0,0 +1,64 @@
+package log
+
+import (
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+/*
+	@func: 按需切换日志文件并写出缓存
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// ForceRotate 立即将所有日志文件切换到新文件，不等待分割间隔
+func ForceRotate() error {
+	rotateWritersMu.Lock()
+	suffixes := make([]string, 0, len(rotateWriters))
+	for suffix := range rotateWriters {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+	writers := make([]RotatingWriter, len(suffixes))
+	for i, suffix := range suffixes {
+		writers[i] = rotateWriters[suffix]
+	}
+	rotateWritersMu.Unlock()
+	var err error
+	for _, writer := range writers {
+		err = multierr.Append(err, writer.Rotate())
+	}
+	return err
+}
+
+// FlushAll 写出批量访问日志的缓存并同步所有logger
+func FlushAll() error {
+	return syncAll()
+}
+
+// HandleRotateSignal 收到sig时先FlushAll再ForceRotate，便于排查问题时获取完整的日志快照，如
+// HandleRotateSignal(syscall.SIGUSR1)。只有调用后才会监听信号，不影响自行处理信号的程序，
+// 返回的stop函数会停止监听并等待后台goroutine退出，可重复调用
+func HandleRotateSignal(sig os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig)
+	stopLoop := startLoop(ch, func(s os.Signal) {
+		// 标准输出为终端或管道时Sync常返回错误，只记录不影响切换
+		flushErr := FlushAll()
+		if err := ForceRotate(); err != nil {
+			errLogger.Error("log: failed to rotate on signal", zap.String("signal", s.String()), zap.Error(err))
+			return
+		}
+		logger.Info("log: flushed and rotated on signal",
+			zap.String("signal", s.String()),
+			zap.NamedError("flush_error", flushErr),
+		)
+	})
+	return func() {
+		signal.Stop(ch)
+		stopLoop()
+	}
+}
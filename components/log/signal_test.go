@@ -0,0 +1,57 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeRotatingWriter 只记录Rotate次数的writer
+type fakeRotatingWriter struct {
+	rotations *atomic.Int64
+}
+
+func (w fakeRotatingWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w fakeRotatingWriter) CurrentFileName() string     { return "" }
+func (w fakeRotatingWriter) Rotate() error {
+	w.rotations.Add(1)
+	return nil
+}
+
+func TestForceRotateConcurrentWithInit(t *testing.T) {
+	chdirTemp(t)
+	var rotations atomic.Int64
+	defer SetWriterFactoryForTest(func(string, RotateConfig, string) (RotatingWriter, error) {
+		return fakeRotatingWriter{&rotations}, nil
+	})()
+	conf := LoggerConfig{Env: "test", DisableStdout: true, NoSymlink: true}
+	if err := InitLoggerWithConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			ForceRotate()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			InitLoggerWithConfig(conf)
+		}
+	}()
+	wg.Wait()
+
+	before := rotations.Load()
+	if err := ForceRotate(); err != nil {
+		t.Fatal(err)
+	}
+	// 普通日志和错误日志各切换一次
+	if got := rotations.Load() - before; got != 2 {
+		t.Fatalf("ForceRotate rotated %d writers, want 2", got)
+	}
+}
@@ -0,0 +1,151 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+	@func: 远程输出共用的异步批量发送队列
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// batchSinkConfig 批量发送的设置，由各输出的配置补全默认值后传入
+type batchSinkConfig struct {
+	// name 输出名，用于发送失败的提示
+	name          string
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+	maxRetries    int
+	retryBackoff  time.Duration
+}
+
+// batchSink 由With派生出的各个core共享的发送队列，攒满一批或每隔flushInterval调用encode编码一次、
+// 按退避重试调用post发送，队列满或重试后仍失败的日志计入DroppedBySink
+type batchSink[T any] struct {
+	conf   batchSinkConfig
+	encode func(batch []T) ([]byte, error)
+	// post 发送一次编码后的请求，返回是否值得重试
+	post  func(body []byte) (retry bool, err error)
+	queue chan T
+	flush chan chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+}
+
+// newBatchSink 创建发送队列并启动发送goroutine
+func newBatchSink[T any](conf batchSinkConfig, encode func([]T) ([]byte, error), post func([]byte) (bool, error)) *batchSink[T] {
+	s := &batchSink[T]{
+		conf:   conf,
+		encode: encode,
+		post:   post,
+		queue:  make(chan T, conf.queueSize),
+		flush:  make(chan chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// enqueue 加入发送队列，队列满时丢弃
+func (s *batchSink[T]) enqueue(item T) {
+	select {
+	case s.queue <- item:
+	default:
+		stats.droppedBySink.Add(1)
+	}
+}
+
+// sync 等待队列中已有的日志发送完成
+func (s *batchSink[T]) sync() {
+	done := make(chan struct{})
+	select {
+	case s.flush <- done:
+		<-done
+	case <-s.done:
+	}
+}
+
+// close 停止发送goroutine，发送完队列中剩余的日志后返回，可重复调用
+func (s *batchSink[T]) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+}
+
+func (s *batchSink[T]) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.conf.flushInterval)
+	defer ticker.Stop()
+	batch := make([]T, 0, s.conf.batchSize)
+	send := func() {
+		if len(batch) > 0 {
+			s.send(batch)
+			batch = batch[:0]
+		}
+	}
+	// drain 取出队列中已有的日志，攒满一批即发送
+	drain := func() {
+		for {
+			select {
+			case item := <-s.queue:
+				if batch = append(batch, item); len(batch) >= s.conf.batchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case item := <-s.queue:
+			if batch = append(batch, item); len(batch) >= s.conf.batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-s.flush:
+			drain()
+			close(done)
+		case <-s.done:
+			drain()
+			return
+		}
+	}
+}
+
+// sinkErrorOutput 发送失败的提示写到这里而不是errLogger，避免经由出错的输出端再次发送形成循环
+var sinkErrorOutput zapcore.WriteSyncer = zapcore.Lock(os.Stderr)
+
+// send 编码后按退避重试发送一批日志，全部失败后整批计入DroppedBySink
+func (s *batchSink[T]) send(batch []T) {
+	body, err := s.encode(batch)
+	if err == nil {
+		backoff := s.conf.retryBackoff
+		for attempt := 0; attempt <= s.conf.maxRetries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+			var retry bool
+			if retry, err = s.post(body); err == nil || !retry {
+				break
+			}
+		}
+	}
+	if err != nil {
+		stats.droppedBySink.Add(uint64(len(batch)))
+		fmt.Fprintf(sinkErrorOutput, "%v log: failed to send %d logs to %s: %v\n", now().UTC(), len(batch), s.conf.name, err)
+		sinkErrorOutput.Sync()
+	}
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyServer 前failures次请求返回503，之后返回200并记录请求体
+type flakyServer struct {
+	mu       sync.Mutex
+	failures int
+	bodies   []string
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	s.bodies = append(s.bodies, string(body))
+	io.WriteString(w, `{"errors":false}`)
+}
+
+func (s *flakyServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func TestExportersRetryThroughSharedSink(t *testing.T) {
+	cases := []struct {
+		name    string
+		newCore func(url string) (zapcore.Core, func() error, error)
+	}{
+		{"elasticsearch", func(url string) (zapcore.Core, func() error, error) {
+			c, err := NewElasticsearchCore(ElasticsearchConfig{URL: url, RetryBackoff: time.Millisecond, FlushInterval: time.Hour})
+			if err != nil {
+				return nil, nil, err
+			}
+			return c, c.Close, nil
+		}},
+		{"otlp", func(url string) (zapcore.Core, func() error, error) {
+			c, err := NewOTLPCore(OTLPConfig{Endpoint: url, RetryBackoff: time.Millisecond, FlushInterval: time.Hour})
+			if err != nil {
+				return nil, nil, err
+			}
+			return c, c.Close, nil
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := &flakyServer{failures: 2}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+			core, closeCore, err := tc.newCore(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeCore()
+			l := zap.New(core).With(zap.String("svc", "api"))
+			l.Info("first")
+			l.Info("second")
+			if err := l.Sync(); err != nil {
+				t.Fatal(err)
+			}
+			bodies := srv.received()
+			if len(bodies) != 1 {
+				t.Fatalf("got %d successful requests, want 1 batch after 2 retries", len(bodies))
+			}
+			for _, want := range []string{"first", "second", "svc"} {
+				if !strings.Contains(bodies[0], want) {
+					t.Errorf("batch %q does not contain %q", bodies[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSinkDropsBatchAfterRetries(t *testing.T) {
+	srv := &flakyServer{failures: 100}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	core, err := NewOTLPCore(OTLPConfig{Endpoint: ts.URL, MaxRetries: 1, RetryBackoff: time.Millisecond, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	defer func(old zapcore.WriteSyncer) { sinkErrorOutput = old }(sinkErrorOutput)
+	sinkErrorOutput = zapcore.AddSync(&stderr)
+	logs, restore := ObserveForTest(zapcore.DebugLevel)
+	defer restore()
+	before := stats.droppedBySink.Load()
+	l := zap.New(core)
+	l.Info("lost")
+	l.Info("lost too")
+	core.Close()
+	if got := stats.droppedBySink.Load() - before; got != 2 {
+		t.Fatalf("dropped %d entries, want 2", got)
+	}
+	// 失败提示不经过errLogger，避免再次写入出错的输出端
+	if logs.Len() != 0 {
+		t.Fatalf("failure reported through the logger: %v", logs.All())
+	}
+	if !strings.Contains(stderr.String(), "failed to send 2 logs") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+	// Close后再次调用不阻塞
+	core.Close()
+	core.Sync()
+}
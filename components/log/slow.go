@@ -0,0 +1,51 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+/*
+	@func: 独立的慢操作日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+var slowLogger = zap.NewNop()
+
+// initSlowLogger 启用时创建只写慢操作日志文件的logger，无法打开文件时返回错误
+func initSlowLogger(conf LoggerConfig, encoder zapcore.Encoder) error {
+	if !conf.SlowLog {
+		slowLogger = zap.NewNop()
+		return nil
+	}
+	writer, err := getLogWriter("-slow.log", conf.SlowRotate, conf.NoSymlink)
+	if err != nil {
+		return err
+	}
+	slowLogger = zap.New(zapcore.NewCore(encoder, writer, zapcore.InfoLevel), zap.AddCaller(), zap.WithClock(packageClock{}))
+	return nil
+}
+
+// SlowLog 耗时超过threshold的操作写入慢操作日志
+type SlowLog struct {
+	threshold time.Duration
+}
+
+// SlowLogger 创建阈值为threshold的慢操作记录器，需在LoggerConfig中启用SlowLog，未启用时不输出
+func SlowLogger(threshold time.Duration) *SlowLog {
+	return &SlowLog{threshold: threshold}
+}
+
+// LogIfSlow 耗时dur超过阈值时将操作op写入慢操作日志，op如"db.Query"、"GET /users"
+func (s *SlowLog) LogIfSlow(op string, dur time.Duration, fields ...zap.Field) {
+	if dur <= s.threshold {
+		return
+	}
+	slowLogger.WithOptions(zap.AddCallerSkip(1)).Info("[slow] "+op, append([]zap.Field{
+		zap.String("op", op),
+		zap.Duration("cost", dur),
+		zap.Duration("threshold", s.threshold),
+	}, fields...)...)
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+/*
+	@func: 单行的精简堆栈
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// defaultStackFrames 精简堆栈默认保留的帧数
+const defaultStackFrames = 10
+
+// compactStack 在recover中调用，返回从panic位置开始的前n帧，格式为func (dir/file.go:line)，以" <- "连接
+func compactStack(n int) string {
+	if n <= 0 {
+		n = defaultStackFrames
+	}
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	var all []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		all = append(all, frame)
+		if !more {
+			break
+		}
+	}
+	// 跳过recover所在的defer函数及runtime.gopanic本身
+	for i, frame := range all {
+		if frame.Function == "runtime.gopanic" {
+			all = all[i+1:]
+			break
+		}
+	}
+	// 空指针、map写入等运行时错误从runtime内部panic，从用户代码开始输出
+	for len(all) > 1 && strings.HasPrefix(all[0].Function, "runtime.") {
+		all = all[1:]
+	}
+	var b strings.Builder
+	for i, frame := range all {
+		if i >= n {
+			break
+		}
+		if i > 0 {
+			b.WriteString(" <- ")
+		}
+		b.WriteString(frame.Function)
+		b.WriteString(" (")
+		b.WriteString(filepath.Join(filepath.Base(filepath.Dir(frame.File)), filepath.Base(frame.File)))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// limitStack 将debug.Stack()的输出截断到从panic位置开始的前depth帧，末尾注明截断的帧数，depth<=0时原样返回，
+// 与compactStack相同，recover所在的defer函数、panic本身及运行时错误的runtime内部帧不计入也不输出
+func limitStack(stack []byte, depth int) string {
+	s := strings.TrimRight(string(stack), "\n")
+	if depth <= 0 {
+		return s
+	}
+	// 第一行为goroutine头，之后每帧占函数和文件位置两行
+	lines := strings.Split(s, "\n")
+	header, frames := lines[0], lines[1:]
+	for i := 0; i+1 < len(frames); i += 2 {
+		if strings.HasPrefix(frames[i], "panic(") {
+			frames = frames[i+2:]
+			break
+		}
+	}
+	for len(frames) > 2 && strings.HasPrefix(frames[0], "runtime.") {
+		frames = frames[2:]
+	}
+	n := len(frames) / 2
+	if n <= depth {
+		return header + "\n" + strings.Join(frames, "\n")
+	}
+	return header + "\n" + strings.Join(frames[:2*depth], "\n") + "\n... " + strconv.Itoa(n-depth) + " frames truncated"
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+//go:noinline
+func panickingFunction() {
+	panic("boom")
+}
+
+//go:noinline
+func nilDerefFunction() {
+	var m *struct{ n int }
+	m.n++
+}
+
+// recoverStack 在recover中获取limitStack和compactStack的结果
+func recoverStack(fn func(), depth int) (limited, compact string) {
+	defer func() {
+		recover()
+		limited = limitStack(debug.Stack(), depth)
+		compact = compactStack(depth)
+	}()
+	fn()
+	return
+}
+
+func TestStackKeepsPanickingFunction(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func()
+		want string
+	}{
+		{"panic", panickingFunction, "log.panickingFunction"},
+		{"runtime error", nilDerefFunction, "log.nilDerefFunction"},
+	}
+	for _, tc := range cases {
+		name, want := tc.name, tc.want
+		limited, compact := recoverStack(tc.fn, 3)
+		lines := strings.Split(limited, "\n")
+		if !strings.HasPrefix(lines[0], "goroutine ") {
+			t.Errorf("%s: missing goroutine header: %q", name, lines[0])
+		}
+		if len(lines) < 2 || !strings.Contains(lines[1], want) {
+			t.Errorf("%s: first frame is not the panicking function:\n%s", name, limited)
+		}
+		if strings.Contains(limited, "runtime/debug.Stack") || strings.Contains(limited, "\npanic(") {
+			t.Errorf("%s: recover frames kept:\n%s", name, limited)
+		}
+		if !strings.HasSuffix(limited, "frames truncated") || strings.Count(limited, "\n\t") != 3 {
+			t.Errorf("%s: want 3 frames then a truncation note:\n%s", name, limited)
+		}
+		if !strings.HasPrefix(compact, "go_components_record/components/"+want) {
+			t.Errorf("%s: compact stack starts at %q", name, compact)
+		}
+	}
+}
+
+func TestLimitStackUnlimited(t *testing.T) {
+	stack := debug.Stack()
+	if got := limitStack(stack, 0); got != strings.TrimRight(string(stack), "\n") {
+		t.Errorf("depth 0 changed the stack")
+	}
+}
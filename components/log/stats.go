@@ -0,0 +1,116 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync/atomic"
+)
+
+/*
+	@func: 日志丢弃计数及退出时的运行汇总
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// LogStats 日志丢弃情况的快照
+type LogStats struct {
+	// DroppedBySampling 被采样丢弃的条数
+	DroppedBySampling uint64 `json:"dropped_by_sampling"`
+	// DroppedByBufferOverflow 因缓冲区已满被丢弃的条数
+	DroppedByBufferOverflow uint64 `json:"dropped_by_buffer_overflow"`
+	// DroppedBySink 写入输出端失败被丢弃的条数
+	DroppedBySink uint64 `json:"dropped_by_sink"`
+}
+
+var stats struct {
+	droppedBySampling       atomic.Uint64
+	droppedByBufferOverflow atomic.Uint64
+	droppedBySink           atomic.Uint64
+	// logsByLevel 按级别统计实际写入的日志条数，下标为级别减去DebugLevel
+	logsByLevel [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Uint64
+	// requests、requestErrors GinLogger处理的请求数及其中5xx的请求数，在采样之前计数
+	requests      atomic.Uint64
+	requestErrors atomic.Uint64
+}
+
+// Stats 获取当前的日志丢弃计数
+func Stats() LogStats {
+	return LogStats{
+		DroppedBySampling:       stats.droppedBySampling.Load(),
+		DroppedByBufferOverflow: stats.droppedByBufferOverflow.Load(),
+		DroppedBySink:           stats.droppedBySink.Load(),
+	}
+}
+
+// countingWriteSyncer 写入失败时计入DroppedBySink
+type countingWriteSyncer struct {
+	zapcore.WriteSyncer
+}
+
+func (w countingWriteSyncer) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	if err != nil {
+		stats.droppedBySink.Add(1)
+	}
+	return n, err
+}
+
+// countLevel 按级别计数
+func countLevel(ent zapcore.Entry) {
+	if ent.Level >= zapcore.DebugLevel && ent.Level <= zapcore.FatalLevel {
+		stats.logsByLevel[ent.Level-zapcore.DebugLevel].Add(1)
+	}
+}
+
+// countCore 在日志通过级别、采样等检查后按级别计数，
+// 与zap.Hooks不同，Write收到forceWriteField时会写入下层core，ContextWithLevel放行的日志不会丢失
+type countCore struct {
+	zapcore.Core
+}
+
+func (c countCore) With(fields []zapcore.Field) zapcore.Core {
+	return countCore{c.Core.With(fields)}
+}
+
+func (c countCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if downstream := c.Core.Check(ent, ce); downstream != nil {
+		return downstream.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 经由Check加入时下层core已各自写入，这里只计数
+func (c countCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	countLevel(ent)
+	if hasForceWrite(fields) {
+		return c.Core.Write(ent, fields)
+	}
+	return nil
+}
+
+// levelCounts 按级别名输出各级别的日志条数，未写入过的级别不输出
+type levelCounts struct{}
+
+func (levelCounts) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for i := range stats.logsByLevel {
+		if n := stats.logsByLevel[i].Load(); n > 0 {
+			enc.AddUint64((zapcore.DebugLevel + zapcore.Level(i)).String(), n)
+		}
+	}
+	return nil
+}
+
+// ShutdownSummary 退出前写入一条运行汇总，包括运行时长、GinLogger处理的请求数和5xx数、各级别的日志条数及丢弃计数，
+// 应在SyncWithTimeout之前调用以确保写出
+func ShutdownSummary(fields ...zap.Field) {
+	dropped := Stats()
+	logger.WithOptions(zap.AddCallerSkip(1)).Info("[shutdown summary]", append([]zap.Field{
+		zap.Duration("uptime", now().Sub(startTime)),
+		zap.Uint64("requests", stats.requests.Load()),
+		zap.Uint64("request_errors", stats.requestErrors.Load()),
+		zap.Object("logs", levelCounts{}),
+		zap.Uint64("dropped_by_sampling", dropped.DroppedBySampling),
+		zap.Uint64("dropped_by_buffer_overflow", dropped.DroppedByBufferOverflow),
+		zap.Uint64("dropped_by_sink", dropped.DroppedBySink),
+	}, fields...)...)
+}
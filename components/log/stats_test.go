@@ -0,0 +1,46 @@
+package log
+
+import (
+	"context"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownSummaryUptimeUsesPackageClock(t *testing.T) {
+	logs, restore := ObserveForTest(zapcore.InfoLevel)
+	defer restore()
+	defer SetClockForTest(&fixedClock{t: startTime.Add(90 * time.Minute)})()
+
+	ShutdownSummary()
+	entry, ok := logs.FindEntry("[shutdown summary]")
+	if !ok {
+		t.Fatal("no shutdown summary")
+	}
+	AssertFieldEquals(t, entry, "uptime", 90*time.Minute)
+}
+
+func TestContextLevelEntriesWrittenAndCounted(t *testing.T) {
+	if err := InitLoggerWithConfig(LoggerConfig{Level: "warn", DisableStdout: true, RecentLogs: 10}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+	debugs := stats.logsByLevel[zapcore.DebugLevel-zapcore.DebugLevel].Load()
+
+	ctx := ContextWithLevel(context.Background(), zapcore.DebugLevel)
+	LoggerFromContext(ctx).Debug("debug for one request")
+	LoggerFromContext(ctx).Warn("warn for one request")
+	var found int
+	for _, line := range RecentLogs() {
+		if strings.Contains(line, "for one request") {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Fatalf("got %d of 2 entries in %q", found, RecentLogs())
+	}
+	if got := stats.logsByLevel[zapcore.DebugLevel-zapcore.DebugLevel].Load() - debugs; got != 1 {
+		t.Fatalf("debug count increased by %d, want 1", got)
+	}
+}
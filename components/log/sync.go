@@ -0,0 +1,42 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/multierr"
+	"time"
+)
+
+/*
+	@func: 退出前刷新日志
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// SyncWithTimeout 写出批量缓存的访问日志并Sync所有logger，超过d仍未完成时返回超时错误，
+// 后台的Sync会继续执行，用于退出流程中避免被卡住的输出端阻塞，例如:
+//
+//	<-ctx.Done() // 收到SIGTERM
+//	srv.Shutdown(shutdownCtx)
+//	log.ShutdownSummary()
+//	if err := log.SyncWithTimeout(3 * time.Second); err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//	}
+func SyncWithTimeout(d time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- syncAll()
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("log: sync timed out after %s", d)
+	}
+}
+
+func syncAll() error {
+	FlushAccessLogs()
+	return multierr.Combine(logger.Sync(), errLogger.Sync(), auditLogger.Sync(), slowLogger.Sync(), panicLogger.Sync())
+}
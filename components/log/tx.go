@@ -0,0 +1,77 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+	@func: 记录数据库事务的开始、提交和回滚
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// TxLog 一个事务的日志，事务内的日志都带有tx_id字段
+type TxLog struct {
+	id     string
+	name   string
+	start  time.Time
+	logger *zap.Logger
+}
+
+// TxLogger 为名为name的事务生成tx_id，返回绑定了带tx_id字段的logger的ctx，
+// 事务内通过LoggerFromContext(ctx)记录的日志都会带上tx_id
+func TxLogger(ctx context.Context, name string) (context.Context, *TxLog) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx := &TxLog{id: randomID(), name: name, start: now()}
+	tx.logger = LoggerFromContext(ctx).With(zap.String("tx_id", tx.id), zap.String("tx", name))
+	return ContextWithLogger(ctx, tx.logger), tx
+}
+
+// randomID 生成16位十六进制的随机ID，用于tx_id、run_id等
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ID 事务ID
+func (tx *TxLog) ID() string {
+	return tx.id
+}
+
+// Logger 带tx_id字段的logger
+func (tx *TxLog) Logger() *zap.Logger {
+	return tx.logger
+}
+
+// Begin 记录事务开始，并从此时开始计算事务耗时
+func (tx *TxLog) Begin(fields ...zap.Field) {
+	tx.start = now()
+	tx.logger.WithOptions(zap.AddCallerSkip(1)).Info("[tx] begin "+tx.name, fields...)
+}
+
+// Commit 记录事务提交及耗时，err不为nil时以Error记录提交失败
+func (tx *TxLog) Commit(err error, fields ...zap.Field) {
+	l := tx.logger.WithOptions(zap.AddCallerSkip(1))
+	fields = append(fields, zap.Duration("cost", now().Sub(tx.start)))
+	if err != nil {
+		l.Error("[tx] commit failed "+tx.name, append(fields, zap.Error(err))...)
+		return
+	}
+	l.Info("[tx] commit "+tx.name, fields...)
+}
+
+// Rollback 以Warn记录事务回滚、回滚原因reason及耗时，reason可以为nil
+func (tx *TxLog) Rollback(reason error, fields ...zap.Field) {
+	fields = append(fields, zap.Duration("cost", now().Sub(tx.start)))
+	if reason != nil {
+		fields = append(fields, zap.NamedError("reason", reason))
+	}
+	tx.logger.WithOptions(zap.AddCallerSkip(1)).Warn("[tx] rollback "+tx.name, fields...)
+}
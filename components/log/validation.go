@@ -0,0 +1,60 @@
+package log
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+/*
+	@func: 记录请求参数校验失败
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// validationErrors 以数组输出的校验失败字段，每项包含field、tag、message，不包含字段的值
+type validationErrors validator.ValidationErrors
+
+func (errs validationErrors) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, fe := range errs {
+		fe := fe
+		enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("field", fe.Namespace())
+			enc.AddString("tag", fe.Tag())
+			enc.AddString("message", validationMessage(fe))
+			return nil
+		}))
+	}
+	return nil
+}
+
+// validationMessage 校验失败的原因，如"failed on 'max=10'"
+func validationMessage(fe validator.FieldError) string {
+	if fe.Param() != "" {
+		return "failed on '" + fe.Tag() + "=" + fe.Param() + "'"
+	}
+	return "failed on '" + fe.Tag() + "'"
+}
+
+// LogValidationError 以Warn记录ShouldBind等返回的参数校验错误，不记录请求体，
+// validator.ValidationErrors以validation_errors数组输出失败的字段，其他错误(如JSON格式错误)只记录错误信息，err为nil时不记录
+func LogValidationError(c *gin.Context, err error, fields ...zap.Field) {
+	if err == nil {
+		return
+	}
+	if c != nil && c.Request != nil && c.Request.URL != nil {
+		fields = append([]zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+		}, fields...)
+	}
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		fields = append(fields, zap.Array("validation_errors", validationErrors(errs)))
+	} else {
+		fields = append(fields, zap.Error(err))
+	}
+	logger.WithOptions(zap.AddCallerSkip(1)).Warn("[validation failed]", fields...)
+}
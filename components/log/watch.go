@@ -0,0 +1,163 @@
+package log
+
+import (
+	"encoding/json"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+/*
+	@func: 监听配置文件，修改后热更新日志配置
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// reloadMu 避免多个WatchConfig同时重建logger
+var reloadMu sync.Mutex
+
+// WatchConfig 监听JSON格式的LoggerConfig配置文件，修改后重新应用级别、采样、编码等配置，
+// 需要重新打开文件的配置(Env、Rotate、Audit等)不会生效，只输出需要重启的警告，
+// 返回的stop函数会停止监听并等待后台goroutine退出，可重复调用
+func WatchConfig(path string) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	path = filepath.Clean(path)
+	// 监听所在目录，编辑器保存时常以重命名替换文件
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+					continue
+				}
+				if err := reloadConfig(path); err != nil {
+					errLogger.Error("log: reload config failed", zap.String("path", path), zap.Error(err))
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				errLogger.Error("log: watch config failed", zap.String("path", path), zap.Error(err))
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			watcher.Close()
+			wg.Wait()
+		})
+	}, nil
+}
+
+// reloadConfig 读取path并在当前生效的配置上应用可热更新的配置项
+func reloadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var conf LoggerConfig
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return err
+	}
+	conf = conf.withDefaults()
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	cur := effectiveConfig
+	if fields := restartFields(cur, conf); len(fields) > 0 {
+		logger.Warn("log: config change requires restart", zap.String("path", path), zap.Strings("fields", fields))
+	}
+	next := cur
+	next.Level = conf.Level
+	next.Sampling = conf.Sampling
+	next.Dedup = conf.Dedup
+	next.DisableStdout = conf.DisableStdout
+	next.DisableErrorStdout = conf.DisableErrorStdout
+	next.StdoutEncoding = conf.StdoutEncoding
+	next.FileEncoding = conf.FileEncoding
+	next.ErrorEncoding = conf.ErrorEncoding
+	next.DurationEncoding = conf.DurationEncoding
+	next.LevelEncoding = conf.LevelEncoding
+	next.CallerEncoding = conf.CallerEncoding
+	next.CallerSegments = conf.CallerSegments
+	next.LineEnding = conf.LineEnding
+	next.ConsoleSeparator = conf.ConsoleSeparator
+	next.SortFields = conf.SortFields
+	next.DevConsole = conf.DevConsole
+	next.MaxFieldLength = conf.MaxFieldLength
+	next.RecentLogs = conf.RecentLogs
+	next.ErrorRateLimit = conf.ErrorRateLimit
+	next.SuppressMessages = conf.SuppressMessages
+	next.SuppressPatterns = conf.SuppressPatterns
+	if reflect.DeepEqual(next, cur) {
+		return nil
+	}
+	next, core, errCore, err := buildCores(next)
+	if err != nil {
+		return err
+	}
+	// 只替换全局logger背后的core，不重新赋值logger等全局变量，处理中的请求可以继续并发使用
+	mainCore.swap(core).Sync()
+	if next.separateErrorLog() {
+		errorCore.swap(errCore).Sync()
+	}
+	effectiveConfig = next
+	logger.Info("log: config reloaded", zap.String("path", path))
+	return nil
+}
+
+// restartFields 需要重启才能生效的配置项中发生变化的项
+func restartFields(cur, conf LoggerConfig) []string {
+	var fields []string
+	if cur.Env != conf.Env {
+		fields = append(fields, "Env")
+	}
+	if cur.NoSymlink != conf.NoSymlink {
+		fields = append(fields, "NoSymlink")
+	}
+	if cur.separateErrorLog() != conf.separateErrorLog() {
+		fields = append(fields, "SeparateErrorLog")
+	}
+	if cur.Rotate != conf.Rotate {
+		fields = append(fields, "Rotate")
+	}
+	if cur.ErrorRotate != conf.ErrorRotate {
+		fields = append(fields, "ErrorRotate")
+	}
+	if cur.Audit != conf.Audit {
+		fields = append(fields, "Audit")
+	}
+	if cur.AuditRotate != conf.AuditRotate {
+		fields = append(fields, "AuditRotate")
+	}
+	if cur.SlowLog != conf.SlowLog {
+		fields = append(fields, "SlowLog")
+	}
+	if cur.SlowRotate != conf.SlowRotate {
+		fields = append(fields, "SlowRotate")
+	}
+	if cur.PanicLog != conf.PanicLog {
+		fields = append(fields, "PanicLog")
+	}
+	if cur.PanicRotate != conf.PanicRotate {
+		fields = append(fields, "PanicRotate")
+	}
+	return fields
+}
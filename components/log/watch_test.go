@@ -0,0 +1,76 @@
+package log
+
+import (
+	"encoding/json"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path string, conf LoggerConfig) {
+	t.Helper()
+	data, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadSwapsCoreWithoutReplacingLoggers(t *testing.T) {
+	conf := LoggerConfig{Level: "info", DisableStdout: true, RecentLogs: 100}
+	if err := InitLoggerWithConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+	global, child := logger, logger.With(zap.String("component", "child"))
+
+	// 热更新期间持续并发写入，配合-race检查
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					logger.Info("concurrent")
+					child.Debug("concurrent debug")
+				}
+			}
+		}()
+	}
+
+	path := filepath.Join(t.TempDir(), "log.json")
+	conf.Level = "debug"
+	writeConfig(t, path, conf)
+	if err := reloadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+	wg.Wait()
+
+	if logger != global {
+		t.Fatal("reload replaced the global logger")
+	}
+	if EffectiveConfig().Level != "debug" {
+		t.Fatalf("effective level = %q, want debug", EffectiveConfig().Level)
+	}
+	child.Debug("after reload")
+	var found bool
+	for _, line := range RecentLogs() {
+		if strings.Contains(line, "after reload") && strings.Contains(line, "child") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("logger derived before reload did not pick up the new level")
+	}
+}
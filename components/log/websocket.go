@@ -0,0 +1,77 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	@func: 记录WebSocket连接的建立、消息计数和关闭
+	@author: Andy_文铎
+	@time: 2023/10/09
+*/
+
+// WSLog 一条WebSocket连接的日志，日志都带有conn_id字段，只统计消息条数和字节数，不记录内容
+type WSLog struct {
+	start    time.Time
+	logger   *zap.Logger
+	msgsIn   atomic.Int64
+	msgsOut  atomic.Int64
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	closed   atomic.Bool
+}
+
+// WSLogger 在升级为WebSocket连接后调用，生成conn_id并以Info记录连接建立，连接结束时需调用Close
+func WSLogger(c *gin.Context, fields ...zap.Field) *WSLog {
+	ws := &WSLog{start: now()}
+	ws.logger = logger.With(zap.String("conn_id", randomID()))
+	if c != nil && c.Request != nil && c.Request.URL != nil {
+		fields = append([]zap.Field{
+			zap.String("path", c.Request.URL.Path),
+			zap.String("ip", c.ClientIP()),
+		}, fields...)
+	}
+	ws.logger.WithOptions(zap.AddCallerSkip(1)).Info("[ws] open", fields...)
+	return ws
+}
+
+// Logger 带conn_id字段的logger，用于记录连接上的其他日志
+func (ws *WSLog) Logger() *zap.Logger {
+	return ws.logger
+}
+
+// MessageIn 统计收到的一条size字节的消息
+func (ws *WSLog) MessageIn(size int) {
+	ws.msgsIn.Add(1)
+	ws.bytesIn.Add(int64(size))
+}
+
+// MessageOut 统计发出的一条size字节的消息
+func (ws *WSLog) MessageOut(size int) {
+	ws.msgsOut.Add(1)
+	ws.bytesOut.Add(int64(size))
+}
+
+// Close 记录连接关闭，包括关闭码、持续时间和收发的消息数，err不为nil时以Warn记录，重复调用只记录一次
+func (ws *WSLog) Close(code int, err error, fields ...zap.Field) {
+	if !ws.closed.CompareAndSwap(false, true) {
+		return
+	}
+	fields = append(fields,
+		zap.Int("close_code", code),
+		zap.Duration("duration", now().Sub(ws.start)),
+		zap.Int64("messages_in", ws.msgsIn.Load()),
+		zap.Int64("messages_out", ws.msgsOut.Load()),
+		zap.Int64("bytes_in", ws.bytesIn.Load()),
+		zap.Int64("bytes_out", ws.bytesOut.Load()),
+	)
+	l := ws.logger.WithOptions(zap.AddCallerSkip(1))
+	if err != nil {
+		l.Warn("[ws] close", append(fields, zap.Error(err))...)
+		return
+	}
+	l.Info("[ws] close", fields...)
+}
@@ -1,18 +1,26 @@
 package log
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
-	"io"
+	"math/rand"
+	"mime"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"path/filepath"
 	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 /*
@@ -21,41 +29,211 @@ import (
 	@time: 2023/10/09
 */
 
+// redactedValue 脱敏后的占位值
+const redactedValue = "***"
+
+// defaultMaxHeaderLength 记录请求头时默认的最大字节数
+const defaultMaxHeaderLength = 256
+
+// logDir 日志文件所在目录
+const logDir = "./log"
+
 var (
+	// initWarnings 初始化期间产生的警告，在logger创建后输出
+	initWarnings []string
+	// rotateWriters 当前使用的日志文件writer，key为文件后缀，由rotateWritersMu保护，
+	// ForceRotate可能在信号处理goroutine中与初始化、热更新并发执行
+	rotateWriters   = map[string]RotatingWriter{}
+	rotateWritersMu sync.Mutex
+	// logWriter、errLogWriter 普通日志和错误日志文件，未写文件时为nil
+	logWriter    zapcore.WriteSyncer
+	errLogWriter zapcore.WriteSyncer
+
 	logger         *zap.Logger
 	sugarLogger    *zap.SugaredLogger
 	errLogger      *zap.Logger
 	sugarErrLogger *zap.SugaredLogger
 )
 
+// InitLogger 按运行环境初始化日志，env为预设名(development、staging、production、ci)时使用对应预设
 func InitLogger(env string) error {
+	if conf, err := PresetConfig(env); err == nil {
+		return InitLoggerWithConfig(conf)
+	}
+	return InitLoggerWithConfig(LoggerConfig{Env: env})
+}
+
+// InitLoggerWithConfig 按配置初始化日志
+func InitLoggerWithConfig(conf LoggerConfig) error {
+	conf = conf.withDefaults()
+	// 在打开日志文件前校验级别，避免配置错误时留下空文件
+	if conf.Level != "" {
+		if _, err := parseLevel(conf.Level); err != nil {
+			return err
+		}
+	}
+	fileEncoder, err := getEncoder(conf.FileEncoding, conf)
+	if err != nil {
+		return err
+	}
+	rotateWritersMu.Lock()
+	rotateWriters = map[string]RotatingWriter{}
+	rotateWritersMu.Unlock()
+	logWriter, errLogWriter = nil, nil
+	if conf.Env == "prod" || conf.Env == "test" {
+		if logWriter, err = getLogWriter(".log", conf.Rotate, conf.NoSymlink); err != nil {
+			return err
+		}
+		if conf.separateErrorLog() {
+			if errLogWriter, err = getLogWriter("-error.log", conf.ErrorRotate, conf.NoSymlink); err != nil {
+				return err
+			}
+		}
+	}
+	// 先打开各独立日志的文件，失败时返回错误而不替换已有的logger
+	if err := initAuditLogger(conf, fileEncoder); err != nil {
+		return err
+	}
+	if err := initSlowLogger(conf, fileEncoder); err != nil {
+		return err
+	}
+	if err := initPanicLogger(conf, fileEncoder); err != nil {
+		return err
+	}
+	conf, core, errCore, err := buildCores(conf)
+	if err != nil {
+		return err
+	}
+	reloadMu.Lock()
+	initLoggers(conf, core, errCore)
+	effectiveConfig = conf
+	reloadMu.Unlock()
+	for _, warning := range initWarnings {
+		logger.Warn(warning)
+	}
+	initWarnings = nil
+	if len(conf.SuppressMessages) > 0 || len(conf.SuppressPatterns) > 0 {
+		logger.Info("log: suppressing messages",
+			zap.Strings("substrings", conf.SuppressMessages),
+			zap.Strings("patterns", conf.SuppressPatterns),
+		)
+	}
+	if conf.SelfTest {
+		return selfTest(conf)
+	}
+	return nil
+}
+
+// outputLevels 标准输出和日志文件的级别，未设置Level时标准输出为debug，日志文件prod为info、其余为debug
+func outputLevels(conf LoggerConfig) (stdoutLevel, fileLevel zapcore.Level, err error) {
+	stdoutLevel, fileLevel = zapcore.DebugLevel, zapcore.DebugLevel
+	if conf.Env == "prod" {
+		fileLevel = zapcore.InfoLevel
+	}
+	if conf.Level != "" {
+		level, err := parseLevel(conf.Level)
+		if err != nil {
+			return 0, 0, err
+		}
+		stdoutLevel, fileLevel = level, level
+	}
+	return stdoutLevel, fileLevel, nil
+}
+
+// buildCores 使用已打开的日志文件按conf创建普通日志和错误日志的core，
+// 返回的conf中不可用而被跳过的输出会被置为关闭
+func buildCores(conf LoggerConfig) (LoggerConfig, zapcore.Core, zapcore.Core, error) {
 	var (
 		allCore      []zapcore.Core
 		allErrorCore []zapcore.Core
 	)
-	writer := getLogWriter(".log")
-	errWriter := getLogWriter("-error.log")
-	encoder := getConsoleEncoder()
-	var l = new(zapcore.Level)
-	l.Set("Debug")
-	allCore = append(allCore, zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), zapcore.DebugLevel))
-	allErrorCore = append(allErrorCore, zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), zapcore.DebugLevel))
-	if env == "prod" {
-		allCore = append(allCore, zapcore.NewCore(encoder, writer, zapcore.InfoLevel))
-		allErrorCore = append(allErrorCore, zapcore.NewCore(encoder, errWriter, zapcore.ErrorLevel))
-	} else if env == "test" {
-		allCore = append(allCore, zapcore.NewCore(encoder, writer, zapcore.DebugLevel))
-		allErrorCore = append(allErrorCore, zapcore.NewCore(encoder, errWriter, zapcore.ErrorLevel))
+	stdoutEncoder, err := getEncoder(conf.StdoutEncoding, conf)
+	if err != nil {
+		return conf, nil, nil, err
 	}
+	fileEncoder, err := getEncoder(conf.FileEncoding, conf)
+	if err != nil {
+		return conf, nil, nil, err
+	}
+	errStdoutEncoder, errFileEncoder := stdoutEncoder, fileEncoder
+	if conf.ErrorEncoding != "" {
+		if errFileEncoder, err = getEncoder(conf.ErrorEncoding, conf); err != nil {
+			return conf, nil, nil, err
+		}
+		errStdoutEncoder = errFileEncoder.Clone()
+	}
+	stdoutLevel, fileLevel, err := outputLevels(conf)
+	if err != nil {
+		return conf, nil, nil, err
+	}
+	var sinks []*groupWriteSyncer
+	if !conf.DisableStdout {
+		stdout := newGroupWriteSyncer(zapcore.Lock(os.Stdout))
+		sinks = append(sinks, stdout)
+		allCore = append(allCore, zapcore.NewCore(stdoutEncoder, stdout, stdoutLevel))
+		if !conf.DisableErrorStdout {
+			allErrorCore = append(allErrorCore, zapcore.NewCore(errStdoutEncoder, zapcore.Lock(os.Stdout), stdoutLevel))
+		}
+	}
+	if conf.Env == "prod" || conf.Env == "test" {
+		file := newGroupWriteSyncer(logWriter)
+		sinks = append(sinks, file)
+		allCore = append(allCore, zapcore.NewCore(fileEncoder, file, fileLevel))
+		if errLogWriter != nil {
+			allErrorCore = append(allErrorCore, zapcore.NewCore(errFileEncoder, errLogWriter, zapcore.ErrorLevel))
+		}
+	}
+	groupSinks.Store(&sinks)
+	if ring := useLogRing(conf.RecentLogs); ring != nil {
+		allCore = append(allCore, zapcore.NewCore(fileEncoder.Clone(), ring, stdoutLevel))
+		allErrorCore = append(allErrorCore, zapcore.NewCore(errFileEncoder.Clone(), ring, zapcore.ErrorLevel))
+	}
+	allCore = append(allCore, conf.Cores...)
 	core := zapcore.NewTee(allCore...)
-	logger = zap.New(core, zap.AddCaller())
+	errCore := zapcore.NewTee(allErrorCore...)
+	if conf.Dedup != nil {
+		core = newDedupCore(core, *conf.Dedup)
+	}
+	if conf.Sampling != nil {
+		core = newSamplerCore(core, conf.Sampling.Initial, conf.Sampling.Thereafter)
+	}
+	core = namedLevelCore{core}
+	errCore = namedLevelCore{errCore}
+	filter, err := newMessageFilter(conf.SuppressMessages, conf.SuppressPatterns)
+	if err != nil {
+		return conf, nil, nil, err
+	}
+	if filter != nil {
+		core = filterCore{Core: core, filter: filter}
+		errCore = filterCore{Core: errCore, filter: filter}
+	}
+	if conf.MaxFieldLength > 0 {
+		core = newTruncateCore(core, conf.MaxFieldLength)
+		errCore = newTruncateCore(errCore, conf.MaxFieldLength)
+	}
+	if conf.ErrorRateLimit > 0 {
+		errCore = newRateCapCore(errCore, conf.ErrorRateLimit)
+	}
+	return conf, core, errCore, nil
+}
+
+// mainCore、errorCore 当前全局logger背后可替换的core，热更新时通过swap替换
+var mainCore, errorCore *reloadableCore
+
+// initLoggers 以core、errCore创建全局的logger和errLogger，之后的热更新只替换core
+func initLoggers(conf LoggerConfig, core, errCore zapcore.Core) {
+	mainCore, errorCore = newReloadableCore(core), newReloadableCore(errCore)
+	logger = zap.New(countCore{mainCore}, zap.AddCaller(), zap.WithClock(packageClock{}))
 	defer logger.Sync()
 	sugarLogger = logger.Sugar()
 	zap.ReplaceGlobals(logger)
-	errCore := zapcore.NewTee(allErrorCore...)
-	errLogger = zap.New(errCore, zap.AddCaller())
+	if !conf.separateErrorLog() {
+		// 错误日志与普通日志共用同一个logger，按级别写入相同的输出端
+		errLogger, sugarErrLogger = logger, sugarLogger
+		return
+	}
+	errLogger = zap.New(countCore{errorCore}, zap.AddCaller(), zap.WithClock(packageClock{}))
 	sugarErrLogger = errLogger.Sugar()
-	return nil
 }
 
 func GetLogInstance() *zap.Logger {
@@ -74,32 +252,562 @@ func GetSugarErrorLogInstance() *zap.SugaredLogger {
 	return sugarErrLogger
 }
 
+// WithoutCaller 不记录调用位置的logger，用于访问日志、心跳等调用位置无意义的日志
+func WithoutCaller() *zap.Logger {
+	return logger.WithOptions(zap.WithCaller(false))
+}
+
+// GinLoggerConfig GinLogger的可选配置
+type GinLoggerConfig struct {
+	// APIVersionHeader 从该请求头读取API版本，如Accept-Version，优先于APIVersionSegment
+	APIVersionHeader string
+	// APIVersionSegment 从路径的第N段读取API版本，如/v1/users的第1段为v1，0表示不从路径读取
+	APIVersionSegment int
+	// CompactSummary 为true时将method、path、status、cost合并为一个request对象字段
+	CompactSummary bool
+	// LogParams 为true时以params对象记录路由参数
+	LogParams bool
+	// RedactParams 记录路由参数时需要脱敏的参数名
+	RedactParams []string
+	// SampleRates 按路由(优先c.FullPath，其次请求路径)设置的采样率，取值0~1
+	SampleRates map[string]float64
+	// DefaultSampleRate 未在SampleRates中的路由的采样率，<=0时为1即全部记录
+	DefaultSampleRate float64
+	// ResponseHeaders 需要记录的响应头白名单，以response_headers对象输出
+	ResponseHeaders []string
+	// ClientIP 自定义客户端IP的获取方式，如读取负载均衡设置的请求头，默认为c.ClientIP
+	ClientIP func(c *gin.Context) string
+	// LogConnInfo 为true时记录连接是否关闭、keep-alive、远端端口，
+	// 服务端设置了ConnContext时还会记录是否复用连接
+	LogConnInfo bool
+	// LogHandlerChain 为true时记录handler链的长度，以及被Instrument包装的各步耗时和中间件总开销
+	LogHandlerChain bool
+	// NoStatusClass 为true时不记录status_class字段，默认按状态码记录2xx、3xx、4xx、5xx
+	NoStatusClass bool
+	// BatchSize、BatchInterval 任一大于0时批量写入访问日志，缓存达到BatchSize条或每隔BatchInterval写出一次，
+	// 未设置的一项使用默认值100条、1秒，退出前需调用FlushAccessLogs写出剩余的日志，
+	// 或调用CloseAccessLogs写出并停止后台的定时写出
+	BatchSize     int
+	BatchInterval time.Duration
+	// SlowThreshold 开启批量写入时，耗时不低于该值的请求与出错的请求一样立即写入，0表示不按耗时区分
+	SlowThreshold time.Duration
+	// LogMultipart 为true时记录multipart/form-data请求上传的文件名、字段名、类型和大小，不记录内容，
+	// 在handler读取请求体时同步解析，handler未读完请求体或解析失败时不记录
+	LogMultipart bool
+	// MaxMultipartFiles 最多记录的文件数，超出时额外记录文件总数，<=0时为20
+	MaxMultipartFiles int
+	// BaggageKeys 从W3C baggage请求头中记录的key白名单，以baggage对象输出，不在白名单中的key忽略
+	BaggageKeys []string
+	// RequestHeaders 需要记录的请求头白名单，以request_headers对象输出，不存在的头不输出
+	RequestHeaders []string
+	// RedactHeaders 记录请求头时需要脱敏的头，为空时使用Authorization、Proxy-Authorization、Cookie、Set-Cookie
+	RedactHeaders []string
+	// MaxHeaderLength 记录的请求头值的最大字节数，超出部分被截断，<=0时为256
+	MaxHeaderLength int
+	// RequestStartHeader 上游代理记录请求开始时间的请求头，如nginx设置的X-Request-Start，
+	// 存在时额外记录upstream_start和进入本服务前的等待时间queue_delay，cost仍为本服务内的耗时
+	RequestStartHeader string
+	// Formatter 自定义访问日志的全部字段，设置后上面控制字段的配置项不再生效，采样和批量写入仍然生效
+	Formatter AccessLogFormatter
+	// LogRequestCounters 为true时在请求的context上安装计数器，记录通过IncrQueryCount、IncrCacheHit、
+	// IncrCacheMiss累计的db_queries、cache_hits、cache_misses
+	LogRequestCounters bool
+	// LogCheckpoints 为true时在请求的context上安装时间线，以timeline字段记录通过Checkpoint添加的各检查点及其间隔
+	LogCheckpoints bool
+	// LogContentType 为true时以content_type记录实际返回的类型(响应Content-Type去掉参数)，请求带有Accept头时一并记录accept，
+	// 便于排查内容协商问题，响应未设置Content-Type时不记录
+	LogContentType bool
+	// AccessLogStyle 访问日志的格式，为AccessLogCombined时以Apache/nginx combined格式的一行作为消息且不带字段，
+	// 便于GoAccess等工具直接分析，此时Formatter及控制字段的配置项不生效，默认为AccessLogStructured
+	AccessLogStyle string
+	// DetectDuplicateRequestID 为true时跟踪处理中请求的X-Request-ID，与处理中的请求重复时以Warn记录，
+	// 用于发现错误复用请求ID的上游代理
+	DetectDuplicateRequestID bool
+	// MaxTrackedRequestIDs 最多同时跟踪的请求ID数，超出时新的请求不再检测，<=0时为10000
+	MaxTrackedRequestIDs int
+	// LazyFields 开销较大的字段，如根据session查询用户，仅在访问日志确定会写入(通过采样和级别检查)时调用，
+	// 结果追加在其他字段之后，Formatter设置时同样生效，AccessLogStyle为AccessLogCombined时不调用
+	LazyFields []LazyAccessField
+	// LogFingerprint 为true时在handler执行前计算请求指纹，记录为fingerprint字段，用于发现重复提交和重试，
+	// 默认由method、路径、FingerprintHeaders及请求体计算，Formatter设置时不记录
+	LogFingerprint bool
+	// FingerprintHeaders 默认指纹包含的请求头，如Idempotency-Key、Authorization
+	FingerprintHeaders []string
+	// FingerprintBody 为true时指纹包含请求体的前MaxFingerprintBody字节，读取后重新放回，不影响handler读取
+	FingerprintBody bool
+	// MaxFingerprintBody 指纹读取的最大请求体字节数，<=0时为64KB
+	MaxFingerprintBody int
+	// Fingerprint 自定义指纹的计算，设置后替代默认的计算方式
+	Fingerprint FingerprintFunc
+}
+
+// LazyAccessField 延迟生成的访问日志字段，不需要记录时可返回zap.Skip()
+type LazyAccessField func(c *gin.Context) zap.Field
+
+// AccessLogStyle的取值
+const (
+	AccessLogStructured = "structured"
+	AccessLogCombined   = "combined"
+)
+
+// AccessLogFormatter 根据请求和耗时生成访问日志的字段，便于各服务共用统一的访问日志格式
+type AccessLogFormatter func(c *gin.Context, cost time.Duration) []zap.Field
+
 // GinLogger 接收gin框架的默认日志
 func GinLogger() gin.HandlerFunc {
+	return GinLoggerWithConfig(GinLoggerConfig{})
+}
+
+// GinLoggerWithConfig 按配置接收gin框架的默认日志
+func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
+	conf = conf.withDefaults()
+	var batch *accessBatch
+	if conf.BatchSize > 0 || conf.BatchInterval > 0 {
+		batch = newAccessBatch(conf.BatchSize, conf.BatchInterval)
+	}
+	var requestIDs *inFlightRequestIDs
+	if conf.DetectDuplicateRequestID {
+		requestIDs = newInFlightRequestIDs(conf.MaxTrackedRequestIDs)
+	}
 	return func(c *gin.Context) {
-		start := time.Now()
+		if requestIDs != nil {
+			defer trackRequestID(c, requestIDs, conf)()
+		}
+		start := now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
+		requestURI := c.Request.URL.RequestURI()
 		ref, _ := c.GetQuery("ref")
+		var connSeq int64
+		if conf.LogConnInfo {
+			connSeq = connRequestSeq(c)
+		}
+		var counters *requestCounters
+		if conf.LogRequestCounters {
+			counters = installRequestCounters(c)
+		}
+		var checkpoints *requestCheckpoints
+		if conf.LogCheckpoints {
+			checkpoints = installCheckpoints(c, start)
+		}
+		var fingerprint string
+		if conf.LogFingerprint {
+			fingerprint = requestFingerprint(c, conf)
+		}
+		var tap *multipartTap
+		if conf.LogMultipart {
+			// handler panic时不会执行到tap.fields，同样需要结束解析goroutine
+			if tap = tapMultipart(c, conf.MaxMultipartFiles); tap != nil {
+				defer tap.close()
+			}
+		}
 		c.Next()
 
-		cost := time.Since(start)
-		logger.Info(path,
+		cost := now().Sub(start)
+		stats.requests.Add(1)
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			stats.requestErrors.Add(1)
+		}
+		var multipartFields []zap.Field
+		if tap != nil {
+			multipartFields = tap.fields()
+		}
+		if !sampled(c, conf) {
+			stats.droppedBySampling.Add(1)
+			return
+		}
+		msg := path
+		if conf.AccessLogStyle == AccessLogCombined {
+			msg = combinedLogLine(c, conf, start, requestURI)
+		}
+		l := WithoutCaller()
+		if batch != nil && c.Writer.Status() < http.StatusInternalServerError && len(c.Errors) == 0 &&
+			(conf.SlowThreshold <= 0 || cost < conf.SlowThreshold) {
+			l = batch.logger()
+		}
+		// 先确认会写入再生成字段，级别或采样不通过时不执行LazyFields等开销较大的操作
+		ce := l.Check(zap.InfoLevel, msg)
+		if ce == nil {
+			return
+		}
+		if conf.AccessLogStyle == AccessLogCombined {
+			ce.Write()
+			return
+		}
+		var fields []zap.Field
+		if conf.Formatter != nil {
+			fields = conf.Formatter(c, cost)
+		} else {
+			fields = accessFields(c, conf, accessInfo{
+				start:           start,
+				cost:            cost,
+				path:            path,
+				query:           query,
+				ref:             ref,
+				connSeq:         connSeq,
+				multipartFields: multipartFields,
+				counters:        counters,
+				checkpoints:     checkpoints,
+				fingerprint:     fingerprint,
+			})
+		}
+		for _, lazy := range conf.LazyFields {
+			fields = append(fields, lazy(c))
+		}
+		ce.Write(fields...)
+	}
+}
+
+// combinedLogLine 生成combined格式的访问日志：
+// ip - user [时间] "method uri proto" status bytes "referer" "user-agent"
+func combinedLogLine(c *gin.Context, conf GinLoggerConfig, start time.Time, requestURI string) string {
+	user := "-"
+	if name, _, ok := c.Request.BasicAuth(); ok && name != "" {
+		user = name
+	}
+	size := c.Writer.Size()
+	if size < 0 {
+		size = 0
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d %s %s",
+		conf.ClientIP(c),
+		user,
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		c.Request.Method, requestURI, c.Request.Proto,
+		c.Writer.Status(),
+		size,
+		combinedQuote(c.Request.Referer()),
+		combinedQuote(c.Request.UserAgent()),
+	)
+}
+
+// combinedQuote 为空时输出"-"，否则转义双引号和反斜杠后加上双引号
+func combinedQuote(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// withDefaults 填充未设置字段的默认值
+func (conf GinLoggerConfig) withDefaults() GinLoggerConfig {
+	if conf.ClientIP == nil {
+		conf.ClientIP = func(c *gin.Context) string {
+			return c.ClientIP()
+		}
+	}
+	if len(conf.RedactHeaders) == 0 {
+		conf.RedactHeaders = defaultRedactHeaders
+	}
+	if conf.MaxHeaderLength <= 0 {
+		conf.MaxHeaderLength = defaultMaxHeaderLength
+	}
+	return conf
+}
+
+// AccessFields 按默认配置生成GinLogger内置的访问日志字段，可在Formatter中调用后追加自定义字段，
+// path、query读取自当前的c.Request，c、c.Request或c.Writer为nil时只返回status和cost
+func AccessFields(c *gin.Context, cost time.Duration) []zap.Field {
+	if c == nil || c.Request == nil || c.Request.URL == nil || c.Writer == nil {
+		return []zap.Field{zap.Int("status", contextStatus(c)), zap.Duration("cost", cost)}
+	}
+	ref, _ := c.GetQuery("ref")
+	return accessFields(c, GinLoggerConfig{}.withDefaults(), accessInfo{
+		start: now().Add(-cost),
+		cost:  cost,
+		path:  c.Request.URL.Path,
+		query: c.Request.URL.RawQuery,
+		ref:   ref,
+	})
+}
+
+// contextStatus 获取响应状态码，c或c.Writer为nil时返回0
+func contextStatus(c *gin.Context) int {
+	if c == nil || c.Writer == nil {
+		return 0
+	}
+	return c.Writer.Status()
+}
+
+// accessInfo 在c.Next前后记录的请求信息，handler可能修改请求，path、query等需在执行前读取
+type accessInfo struct {
+	start           time.Time
+	cost            time.Duration
+	path            string
+	query           string
+	ref             string
+	connSeq         int64
+	multipartFields []zap.Field
+	counters        *requestCounters
+	checkpoints     *requestCheckpoints
+	fingerprint     string
+}
+
+// accessFields 内置的访问日志字段
+func accessFields(c *gin.Context, conf GinLoggerConfig, info accessInfo) []zap.Field {
+	var fields []zap.Field
+	if conf.CompactSummary {
+		fields = append(fields, RequestSummary(c, info.cost))
+	} else {
+		fields = append(fields,
 			zap.Int("status", c.Writer.Status()),
 			zap.String("method", c.Request.Method),
-			zap.String("path", path),
-			zap.String("query", query),
-			zap.String("ip", c.ClientIP()),
-			zap.String("user-agent", c.Request.UserAgent()),
-			zap.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()),
-			zap.Duration("cost", cost),
-			zap.String("ref", ref),
+			zap.String("path", info.path),
 		)
 	}
+	if !conf.NoStatusClass {
+		fields = append(fields, zap.String("status_class", statusClass(c.Writer.Status())))
+	}
+	fields = append(fields,
+		zap.String("query", info.query),
+		zap.String("ip", conf.ClientIP(c)),
+		zap.String("user-agent", c.Request.UserAgent()),
+		zap.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()),
+	)
+	if !conf.CompactSummary {
+		fields = append(fields, zap.Duration("cost", info.cost))
+	}
+	fields = append(fields, zap.String("ref", info.ref))
+	if version := apiVersion(c, conf); version != "" {
+		fields = append(fields, zap.String("api_version", version))
+	}
+	if conf.LogParams && len(c.Params) > 0 {
+		fields = append(fields, zap.Object("params", routeParams{params: append(gin.Params(nil), c.Params...), redact: conf.RedactParams}))
+	}
+	if conf.RequestStartHeader != "" {
+		if upstream, ok := parseRequestStart(c.GetHeader(conf.RequestStartHeader)); ok {
+			fields = append(fields,
+				zap.Time("upstream_start", upstream),
+				zap.Duration("queue_delay", info.start.Sub(upstream)),
+			)
+		}
+	}
+	if baggage := pickBaggage(c.Request.Header, conf.BaggageKeys); baggage != nil {
+		fields = append(fields, zap.Object("baggage", baggage))
+	}
+	fields = append(fields, info.multipartFields...)
+	if info.counters != nil {
+		fields = append(fields, info.counters.fields()...)
+	}
+	if info.checkpoints != nil {
+		fields = append(fields, info.checkpoints.field())
+	}
+	if conf.LogHandlerChain {
+		fields = append(fields, chainFields(c, info.cost)...)
+	}
+	if conf.LogConnInfo {
+		fields = append(fields, connFields(c, info.connSeq)...)
+	}
+	if headers := redactHeaders(pickHeaders(c.Request.Header, conf.RequestHeaders), conf.RedactHeaders, conf.MaxHeaderLength); headers != nil {
+		fields = append(fields, zap.Object("request_headers", headers))
+	}
+	if headers := pickHeaders(c.Writer.Header(), conf.ResponseHeaders); headers != nil {
+		fields = append(fields, zap.Object("response_headers", headers))
+	}
+	if conf.LogContentType {
+		fields = append(fields, contentTypeFields(c)...)
+	}
+	if info.fingerprint != "" {
+		fields = append(fields, zap.String("fingerprint", info.fingerprint))
+	}
+	return fields
+}
+
+// contentTypeFields 响应的媒体类型及请求的Accept头，响应未设置Content-Type时返回nil
+func contentTypeFields(c *gin.Context) []zap.Field {
+	mediaType, _, err := mime.ParseMediaType(c.Writer.Header().Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+	fields := []zap.Field{zap.String("content_type", mediaType)}
+	if accept := c.GetHeader("Accept"); accept != "" {
+		fields = append(fields, zap.String("accept", accept))
+	}
+	return fields
+}
+
+// sampled 按路由采样率判断是否记录本次请求，出错的请求总是记录
+func sampled(c *gin.Context, conf GinLoggerConfig) bool {
+	if c.Writer.Status() >= http.StatusBadRequest || len(c.Errors) > 0 {
+		return true
+	}
+	rate, ok := conf.SampleRates[c.FullPath()]
+	if !ok {
+		rate, ok = conf.SampleRates[c.Request.URL.Path]
+	}
+	if !ok {
+		rate = conf.DefaultSampleRate
+		if rate <= 0 {
+			return true
+		}
+	}
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// statusClass 状态码所属的类别，如503为5xx
+func statusClass(status int) string {
+	if status < 100 || status > 999 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// requestSummary 请求的精简摘要，作为一个嵌套对象输出
+type requestSummary struct {
+	method string
+	path   string
+	status int
+	cost   time.Duration
+}
+
+func (r requestSummary) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("method", r.method)
+	enc.AddString("path", r.path)
+	enc.AddInt("status", r.status)
+	enc.AddDuration("cost", r.cost)
+	return nil
+}
+
+// RequestSummary 将请求的method、path、status和耗时合并为一个request字段，c或c.Request为nil时method和path为空
+func RequestSummary(c *gin.Context, cost time.Duration) zap.Field {
+	summary := requestSummary{status: contextStatus(c), cost: cost}
+	if c != nil && c.Request != nil {
+		summary.method = c.Request.Method
+		if c.Request.URL != nil {
+			summary.path = c.Request.URL.Path
+		}
+	}
+	return zap.Object("request", summary)
+}
+
+// routeParams 路由参数，redact中的参数值会被脱敏，
+// 编码可能延迟到批量写入时，gin会复用Context及其Params，需传入复制的params
+type routeParams struct {
+	params gin.Params
+	redact []string
+}
+
+func (r routeParams) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, param := range r.params {
+		value := param.Value
+		for _, key := range r.redact {
+			if param.Key == key {
+				value = redactedValue
+				break
+			}
+		}
+		enc.AddString(param.Key, value)
+	}
+	return nil
+}
+
+// headerValues 白名单中实际存在的请求头或响应头
+type headerValues []headerValue
+
+type headerValue struct {
+	name  string
+	value string
+}
+
+func (h headerValues) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, v := range h {
+		enc.AddString(v.name, v.value)
+	}
+	return nil
+}
+
+// pickHeaders 按白名单挑出存在的头，一个都没有时返回nil
+func pickHeaders(header http.Header, names []string) headerValues {
+	var picked headerValues
+	for _, name := range names {
+		if values := header.Values(name); len(values) > 0 {
+			picked = append(picked, headerValue{name: http.CanonicalHeaderKey(name), value: strings.Join(values, ", ")})
+		}
+	}
+	return picked
+}
+
+// redactHeaders 将redact中的头替换为redactedValue，其余的值去掉控制字符，max大于0时截断超过max字节的值
+func redactHeaders(picked headerValues, redact []string, max int) headerValues {
+	for i := range picked {
+		for _, name := range redact {
+			if strings.EqualFold(picked[i].name, name) {
+				picked[i].value = redactedValue
+				break
+			}
+		}
+		picked[i].value = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, picked[i].value)
+		if max > 0 {
+			picked[i].value = truncateString(picked[i].value, max)
+		}
+	}
+	return picked
+}
+
+// parseRequestStart 解析上游记录的请求开始时间，支持t=前缀，按数值大小识别秒、毫秒和微秒
+func parseRequestStart(value string) (time.Time, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "t=")
+	if value == "" {
+		return time.Time{}, false
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case v > 1e15:
+		return time.UnixMicro(int64(v)), true
+	case v > 1e12:
+		return time.UnixMilli(int64(v)), true
+	}
+	return time.Unix(0, int64(v*float64(time.Second))), true
+}
+
+// apiVersion 按配置从请求头或路径中提取API版本，取不到时返回空串
+func apiVersion(c *gin.Context, conf GinLoggerConfig) string {
+	if conf.APIVersionHeader != "" {
+		if version := c.GetHeader(conf.APIVersionHeader); version != "" {
+			return version
+		}
+	}
+	if conf.APIVersionSegment > 0 {
+		segments := strings.Split(strings.Trim(c.Request.URL.Path, "/"), "/")
+		if conf.APIVersionSegment <= len(segments) {
+			return segments[conf.APIVersionSegment-1]
+		}
+	}
+	return ""
+}
+
+// RecoveryConfig GinRecovery的可选配置
+type RecoveryConfig struct {
+	// Stack 为true时记录panic的堆栈，默认为单行的精简堆栈
+	Stack bool
+	// StackFrames 精简堆栈保留的帧数，<=0时为10
+	StackFrames int
+	// VerboseStack 为true时记录debug.Stack()的完整堆栈，而不是精简堆栈
+	VerboseStack bool
+	// MaxStackDepth 完整堆栈最多记录的帧数，超出部分截断并注明截断的帧数，<=0时不限制
+	MaxStackDepth int
+	// ResponseHandler 自定义panic后的响应，默认返回空body的500，连接已断开时不会调用
+	ResponseHandler func(c *gin.Context, err interface{})
+	// RePanic 为true时记录后重新panic，交给外层的recover处理，没有外层recover时进程退出，
+	// 此时不会调用ResponseHandler，默认false即返回500
+	RePanic bool
 }
 
 // GinRecovery recover掉项目可能出现的panic
 func GinRecovery(stack bool) gin.HandlerFunc {
+	return GinRecoveryWithConfig(RecoveryConfig{Stack: stack})
+}
+
+// GinRecoveryWithConfig 按配置recover掉项目可能出现的panic
+func GinRecoveryWithConfig(conf RecoveryConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
@@ -115,64 +823,270 @@ func GinRecovery(stack bool) gin.HandlerFunc {
 				}
 
 				httpRequest, _ := httputil.DumpRequest(c.Request, false)
+				fields := append(PanicFields(err), zap.String("request", string(httpRequest)))
 				if brokenPipe {
-					errLogger.Error(c.Request.URL.Path,
-						zap.Any("error", err),
-						zap.String("request", string(httpRequest)),
-					)
+					errLogger.Error(c.Request.URL.Path, fields...)
 					// If the connection is dead, we can't write a status to it.
 					c.Error(err.(error)) // nolint: errcheck
 					c.Abort()
 					return
 				}
 
-				if stack {
-					errLogger.Error("[Recovery from panic]",
-						zap.Any("error", err),
-						zap.String("request", string(httpRequest)),
-						zap.String("stack", string(debug.Stack())),
-					)
+				if conf.Stack && conf.VerboseStack {
+					fields = append(fields, zap.String("stack", limitStack(debug.Stack(), conf.MaxStackDepth)))
+				} else if conf.Stack {
+					fields = append(fields, zap.String("stack", compactStack(conf.StackFrames)))
+				}
+				logPanic("[Recovery from panic]", fields...)
+				if conf.RePanic {
+					// 进程可能随之退出，先确保日志落盘
+					errLogger.Sync()
+					panic(err)
+				}
+				if conf.ResponseHandler != nil {
+					conf.ResponseHandler(c, err)
+					c.Abort()
 				} else {
-					errLogger.Error("[Recovery from panic]",
-						zap.Any("error", err),
-						zap.String("request", string(httpRequest)),
-					)
+					c.AbortWithStatus(http.StatusInternalServerError)
 				}
-				c.AbortWithStatus(http.StatusInternalServerError)
 			}
 		}()
 		c.Next()
 	}
 }
 
-func getConsoleEncoder() zapcore.Encoder {
+// PanicFields recover得到的panic值本身及其具体类型，值实现了error时额外记录Error()，供其他框架的recover中间件使用
+func PanicFields(err interface{}) []zap.Field {
+	fields := []zap.Field{
+		zap.Any("error", err),
+		zap.String("panic_type", fmt.Sprintf("%T", err)),
+	}
+	if e, ok := err.(error); ok {
+		fields = append(fields, zap.String("panic_error", e.Error()))
+	}
+	return fields
+}
+
+// getEncoder 按名称获取编码器
+func getEncoder(encoding string, conf LoggerConfig) (zapcore.Encoder, error) {
+	encoderConfig, err := newEncoderConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	applyEncoderOptions(&encoderConfig, conf)
+	var encoder zapcore.Encoder
+	switch encoding {
+	case EncodingConsole:
+		encoder = getConsoleEncoder(encoderConfig)
+		if conf.DevConsole {
+			encoder = newDevConsoleEncoder(encoder, encoderConfig.LineEnding)
+		}
+	case EncodingJSON:
+		encoder = getJsonEncoder(encoderConfig)
+	case EncodingLogfmt:
+		encoder = newLogfmtEncoder(encoderConfig)
+	case EncodingGCP:
+		encoder = newGCPEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("log: unknown encoding %q, want %q, %q, %q or %q", encoding, EncodingConsole, EncodingJSON, EncodingLogfmt, EncodingGCP)
+	}
+	if conf.SortFields {
+		encoder = sortedEncoder{encoder}
+	}
+	return encoder, nil
+}
+
+// sortedEncoder 编码前按key排序单条日志的字段，With添加的字段已提前编码，保持原有顺序
+type sortedEncoder struct {
+	zapcore.Encoder
+}
+
+func (e sortedEncoder) Clone() zapcore.Encoder {
+	return sortedEncoder{e.Encoder.Clone()}
+}
+
+func (e sortedEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	sorted := make([]zapcore.Field, len(fields))
+	copy(sorted, fields)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+	return e.Encoder.EncodeEntry(ent, sorted)
+}
+
+// newEncoderConfig 两种编码器共用的编码配置
+func newEncoderConfig(conf LoggerConfig) (zapcore.EncoderConfig, error) {
+	if conf.EncoderConfig != nil {
+		return customEncoderConfig(conf)
+	}
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = customTimeEncoder
 	encoderConfig.TimeKey = "time"
-	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
-	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	levelEncoder, err := getLevelEncoder(conf.LevelEncoding)
+	if err != nil {
+		return encoderConfig, err
+	}
+	encoderConfig.EncodeLevel = levelEncoder
+	callerEncoder, err := getCallerEncoder(conf.CallerEncoding, conf.CallerSegments)
+	if err != nil {
+		return encoderConfig, err
+	}
+	encoderConfig.EncodeCaller = callerEncoder
+	durationEncoder, err := getDurationEncoder(conf.DurationEncoding)
+	if err != nil {
+		return encoderConfig, err
+	}
+	encoderConfig.EncodeDuration = durationEncoder
+	return encoderConfig, nil
+}
+
+// applyEncoderOptions 无论基础编码配置来自何处，都按配置覆盖换行符和分隔符
+func applyEncoderOptions(encoderConfig *zapcore.EncoderConfig, conf LoggerConfig) {
+	if conf.LineEnding != "" {
+		encoderConfig.LineEnding = conf.LineEnding
+	}
+	if conf.ConsoleSeparator != "" {
+		encoderConfig.ConsoleSeparator = conf.ConsoleSeparator
+	}
+}
+
+// customEncoderConfig 以用户传入的编码配置为基础，只补全其未设置的时间、级别、调用位置和时长编码
+func customEncoderConfig(conf LoggerConfig) (zapcore.EncoderConfig, error) {
+	encoderConfig := *conf.EncoderConfig
+	if encoderConfig.TimeKey == "" {
+		encoderConfig.TimeKey = "time"
+		encoderConfig.EncodeTime = customTimeEncoder
+	}
+	if encoderConfig.EncodeLevel == nil {
+		levelEncoder, err := getLevelEncoder(conf.LevelEncoding)
+		if err != nil {
+			return encoderConfig, err
+		}
+		encoderConfig.EncodeLevel = levelEncoder
+	}
+	if encoderConfig.EncodeCaller == nil {
+		callerEncoder, err := getCallerEncoder(conf.CallerEncoding, conf.CallerSegments)
+		if err != nil {
+			return encoderConfig, err
+		}
+		encoderConfig.EncodeCaller = callerEncoder
+	}
+	if encoderConfig.EncodeDuration == nil {
+		durationEncoder, err := getDurationEncoder(conf.DurationEncoding)
+		if err != nil {
+			return encoderConfig, err
+		}
+		encoderConfig.EncodeDuration = durationEncoder
+	}
+	return encoderConfig, nil
+}
+
+func getConsoleEncoder(encoderConfig zapcore.EncoderConfig) zapcore.Encoder {
 	return zapcore.NewConsoleEncoder(encoderConfig)
 }
 
-func getJsonEncoder() zapcore.Encoder {
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.EncodeTime = customTimeEncoder
-	encoderConfig.TimeKey = "time"
-	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
-	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+func getJsonEncoder(encoderConfig zapcore.EncoderConfig) zapcore.Encoder {
 	return zapcore.NewJSONEncoder(encoderConfig)
 }
 
-func getLogWriter(suffix string) zapcore.WriteSyncer {
-	writer, err := getWriter(suffix)
+// getDurationEncoder 按名称获取时长编码方式
+func getDurationEncoder(name string) (zapcore.DurationEncoder, error) {
+	switch name {
+	case DurationSeconds:
+		return zapcore.SecondsDurationEncoder, nil
+	case DurationMillis:
+		return zapcore.MillisDurationEncoder, nil
+	case DurationNanos:
+		return zapcore.NanosDurationEncoder, nil
+	case DurationString:
+		return zapcore.StringDurationEncoder, nil
+	default:
+		return nil, fmt.Errorf("log: unknown duration encoding %q", name)
+	}
+}
+
+// getLevelEncoder 按名称获取级别编码方式
+func getLevelEncoder(name string) (zapcore.LevelEncoder, error) {
+	switch name {
+	case LevelCapital:
+		return zapcore.CapitalLevelEncoder, nil
+	case LevelLower:
+		return zapcore.LowercaseLevelEncoder, nil
+	case LevelNumber:
+		return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt8(int8(l))
+		}, nil
+	default:
+		return nil, fmt.Errorf("log: unknown level encoding %q", name)
+	}
+}
+
+// getCallerEncoder 按名称获取调用位置编码方式
+func getCallerEncoder(name string, segments int) (zapcore.CallerEncoder, error) {
+	switch name {
+	case CallerShort:
+		return zapcore.ShortCallerEncoder, nil
+	case CallerFull:
+		return zapcore.FullCallerEncoder, nil
+	case CallerPackage:
+		if segments <= 0 {
+			segments = 2
+		}
+		return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+			if !caller.Defined {
+				enc.AppendString("undefined")
+				return
+			}
+			enc.AppendString(lastPathSegments(caller.File, segments) + ":" + strconv.Itoa(caller.Line))
+		}, nil
+	default:
+		return nil, fmt.Errorf("log: unknown caller encoding %q", name)
+	}
+}
+
+// lastPathSegments 保留path的最后n段
+func lastPathSegments(path string, n int) string {
+	i := len(path)
+	for ; n > 0; n-- {
+		if i = strings.LastIndexByte(path[:i], '/'); i < 0 {
+			return path
+		}
+	}
+	return path[i+1:]
+}
+
+// getLogWriter 创建后缀为suffix的日志文件writer，无法创建日志目录或writer时返回错误
+func getLogWriter(suffix string, rotate RotateConfig, noSymlink bool) (zapcore.WriteSyncer, error) {
+	// rotatelogs在第一次写入时才打开文件，先创建目录以便在初始化时发现权限等问题
+	if err := fsys.MkdirAll(streamDir(suffix), 0755); err != nil {
+		return nil, fmt.Errorf("log: create directory for %s: %w", suffix, err)
+	}
+	linkName := "zap" + suffix
+	if noSymlink || !symlinkSupported(filepath.Dir(linkName)) {
+		linkName = ""
+	}
+	writer, err := writerFactory(suffix, rotate, linkName)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("log: open %s: %w", suffix, err)
 	}
-	return zapcore.AddSync(writer)
+	rotateWritersMu.Lock()
+	rotateWriters[suffix] = writer
+	rotateWritersMu.Unlock()
+	return countingWriteSyncer{zapcore.AddSync(writer)}, nil
 }
 
-// getWriter 日志文件分割，按小时
-func getWriter(suffix string) (io.Writer, error) {
+// streamDir 后缀为suffix的日志文件所在目录，普通日志在logDir，其他日志在logDir下以后缀命名的子目录，如log/error，
+// rotatelogs按文件名模式生成清理用的glob，普通日志的log/zap-*-*.log在同一目录下会匹配到其他日志的文件，
+// 分目录后各日志只按各自的MaxAge清理
+func streamDir(suffix string) string {
+	if suffix == ".log" {
+		return logDir
+	}
+	return logDir + "/" + strings.TrimSuffix(strings.TrimPrefix(suffix, "-"), ".log")
+}
+
+// getWriter 日志文件分割，按rotate设置的间隔分割并清理过期文件，linkName非空时创建指向当前文件的软链接
+func getWriter(suffix string, rotate RotateConfig, linkName string) (RotatingWriter, error) {
 	//hook, err := rotatelogs.New(
 	//	"/opt/logs/eva-inquire/log/zap-%Y%m%d-%H"+suffix,
 	//	rotatelogs.WithLinkName("zap"+suffix),
@@ -180,18 +1094,42 @@ func getWriter(suffix string) (io.Writer, error) {
 	//	rotatelogs.WithRotationTime(time.Hour),
 	//)
 
-	hook, err := rotatelogs.New(
-		"./log/zap-%Y%m%d-%H%M"+suffix,
-		rotatelogs.WithLinkName("zap"+suffix),
-		rotatelogs.WithMaxAge(time.Hour*24*7),
-		rotatelogs.WithRotationTime(time.Minute),
-	)
+	options := []rotatelogs.Option{
+		rotatelogs.WithMaxAge(rotate.MaxAge),
+		rotatelogs.WithRotationTime(rotate.RotationTime),
+		rotatelogs.WithHandler(rotationHandler(suffix, rotate)),
+		rotatelogs.WithClock(packageClock{}),
+	}
+	if linkName != "" {
+		options = append(options, rotatelogs.WithLinkName(linkName))
+	}
+	pattern := streamDir(suffix) + "/zap-%Y%m%d-%H%M" + suffix
+	if rotate.DateDirs {
+		pattern = streamDir(suffix) + "/%Y/%m/%d/zap-%Y%m%d-%H%M" + suffix
+	}
+	hook, err := rotatelogs.New(pattern, options...)
 	if err != nil {
 		return nil, err
 	}
 	return hook, nil
 }
 
+// symlinkSupported 检测dir下能否创建软链接，部分overlay、NFS文件系统不支持，不支持时记录警告
+func symlinkSupported(dir string) bool {
+	probe := filepath.Join(dir, ".symlink_probe")
+	err := fsys.MkdirAll(dir, 0755)
+	if err == nil {
+		fsys.Remove(probe)
+		err = fsys.Symlink("symlink_probe_target", probe)
+	}
+	if err != nil {
+		initWarnings = append(initWarnings, fmt.Sprintf("log: symlink not supported in %s, rotating without link: %v", dir, err))
+		return false
+	}
+	fsys.Remove(probe)
+	return true
+}
+
 func customTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	enc.AppendString(t.Format("2006-01-02 15:04:05"))
 }
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteParamsCopiedForBatchedAccessLogs(t *testing.T) {
+	if err := InitLoggerWithConfig(LoggerConfig{DisableStdout: true, RecentLogs: 10, FileEncoding: EncodingJSON}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitLoggerWithConfig(LoggerConfig{DisableStdout: true}) })
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinLoggerWithConfig(GinLoggerConfig{LogParams: true, BatchSize: 100, BatchInterval: time.Hour}))
+	r.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	// 顺序执行的请求复用同一个pooled Context，批量写入时第一个请求已被覆盖
+	for _, id := range []string{"1", "2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/"+id, nil))
+	}
+	FlushAccessLogs()
+
+	var checked int
+	for _, line := range RecentLogs() {
+		for _, id := range []string{"1", "2"} {
+			if strings.Contains(line, `"path":"/users/`+id+`"`) {
+				checked++
+				if !strings.Contains(line, `"params":{"id":"`+id+`"}`) {
+					t.Errorf("access log for /users/%s has wrong params: %s", id, line)
+				}
+			}
+		}
+	}
+	if checked != 2 {
+		t.Fatalf("found %d access logs, want 2: %v", checked, RecentLogs())
+	}
+}